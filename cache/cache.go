@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound key 不存在或已过期
+var ErrNotFound = errors.New("cache: key not found")
+
+// Cache 通用的缓存接口，值统一为字节切片，需要存取结构体时使用 Typed
+// ttl 为 0 表示永不过期
+type Cache interface {
+	// Get 获取缓存，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// MGet 批量获取，返回结果中只包含存在的 key
+	MGet(ctx context.Context, keys ...string) (map[string][]byte, error)
+	// Incr 将 key 的整数值增加 delta，key 不存在时从 0 开始
+	Incr(ctx context.Context, key string, delta int64) (int64, error)
+}
+
+// Typed 在 Cache 之上按 T 类型存取，通过 Codec 序列化
+//
+//	users := cache.NewTyped[User](cache.NewMemory(10000), cache.JSON)
+//	_ = users.Set(ctx, "user:1", user, time.Minute)
+//	user, err := users.Get(ctx, "user:1")
+type Typed[T any] struct {
+	cache Cache
+	codec Codec
+}
+
+// NewTyped 创建类型化的缓存，codec 为 nil 时使用 JSON
+func NewTyped[T any](c Cache, codec Codec) *Typed[T] {
+	if codec == nil {
+		codec = JSON
+	}
+	return &Typed[T]{cache: c, codec: codec}
+}
+
+// Get 获取缓存并反序列化，不存在时返回 ErrNotFound
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	data, err := t.cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	err = t.codec.Unmarshal(data, &value)
+	return value, err
+}
+
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.cache.Set(ctx, key, data, ttl)
+}
+
+func (t *Typed[T]) Delete(ctx context.Context, keys ...string) error {
+	return t.cache.Delete(ctx, keys...)
+}
+
+// MGet 批量获取，返回结果中只包含存在的 key
+func (t *Typed[T]) MGet(ctx context.Context, keys ...string) (map[string]T, error) {
+	values, err := t.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(values))
+	for key, data := range values {
+		var value T
+		if err := t.codec.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/zhangc-zwl/thunder/gorms"
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+const (
+	// DefaultNotFoundTTL 数据不存在时的缓存时间，防止不存在的 key 反复穿透到数据库
+	DefaultNotFoundTTL = 30 * time.Second
+	// DefaultJitter TTL 的默认随机浮动比例，避免同一批 key 同时过期
+	DefaultJitter = 0.1
+)
+
+// 不存在的数据在缓存中的占位值
+var notFoundValue = []byte("\x00cache:not_found")
+
+var loadGroup singleflight.Group
+
+// Loader 旁路缓存加载器：先查缓存，未命中时调用 load 回源并写回缓存
+//
+//	var userLoader = &cache.Loader[User]{Name: "user", Cache: store, TTL: time.Hour}
+//	user, err := userLoader.Get(ctx, "user:"+id, func(ctx context.Context) (User, error) {
+//		var u User
+//		err := db.WithContext(ctx).First(&u, id).Error
+//		return u, err
+//	})
+type Loader[T any] struct {
+	// Name 指标名称，为空时使用 "default"
+	Name  string
+	Cache Cache
+	// Codec 为空时使用 JSON
+	Codec Codec
+	TTL   time.Duration
+	// NotFoundTTL 数据不存在时的缓存时间，为 0 时使用 DefaultNotFoundTTL，小于 0 时不缓存
+	NotFoundTTL time.Duration
+	// Jitter TTL 的随机浮动比例，为 0 时使用 DefaultJitter，小于 0 时不浮动
+	Jitter float64
+}
+
+// GetOrLoad 使用默认配置的 Loader 读取缓存
+func GetOrLoad[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	l := &Loader[T]{Cache: c, TTL: ttl}
+	return l.Get(ctx, key, load)
+}
+
+// Get 读取缓存，未命中时回源
+// 同一实例内相同 key 的并发回源会被合并；load 返回 gorm.ErrRecordNotFound 或 ErrNotFound 时会短暂缓存不存在的结果，
+// 之后命中时返回 gorm.ErrRecordNotFound，调用方可以继续使用 gorms.IsRecordNotFoundError 判断
+func (l *Loader[T]) Get(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	stats := loaderStats(l.Name)
+	codec := l.Codec
+	if codec == nil {
+		codec = JSON
+	}
+	data, err := l.Cache.Get(ctx, key)
+	if err == nil {
+		if bytes.Equal(data, notFoundValue) {
+			stats.negativeHits.Add(1)
+			return zero, gorm.ErrRecordNotFound
+		}
+		var value T
+		decErr := codec.Unmarshal(data, &value)
+		if decErr == nil {
+			stats.hits.Add(1)
+			return value, nil
+		}
+		// 数据结构变化导致无法解析时当作未命中处理
+		logs.CtxWarn(ctx, "cache value decode failed", "key", key, "error", decErr)
+	} else if !errors.Is(err, ErrNotFound) {
+		// 缓存不可用时直接回源，不影响业务
+		logs.CtxWarn(ctx, "cache get failed", "key", key, "error", err)
+	}
+	stats.misses.Add(1)
+
+	// 不同类型的 Loader 可能使用相同的 key，合并时按类型区分
+	flightKey := fmt.Sprintf("%T:%s", zero, key)
+	result, err, _ := loadGroup.Do(flightKey, func() (any, error) {
+		// 合并后的回源不应因为某一个请求取消而让其他等待者一起失败
+		ctx := context.WithoutCancel(ctx)
+		value, err := load(ctx)
+		if err != nil {
+			if gorms.IsRecordNotFoundError(err) || errors.Is(err, ErrNotFound) {
+				if ttl := l.notFoundTTL(); ttl > 0 {
+					if err := l.Cache.Set(ctx, key, notFoundValue, l.jitter(ttl)); err != nil {
+						logs.CtxWarn(ctx, "cache set failed", "key", key, "error", err)
+					}
+				}
+				return nil, gorm.ErrRecordNotFound
+			}
+			stats.loadErrors.Add(1)
+			return nil, err
+		}
+		data, err := codec.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.Cache.Set(ctx, key, data, l.jitter(l.TTL)); err != nil {
+			logs.CtxWarn(ctx, "cache set failed", "key", key, "error", err)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	return result.(T), nil
+}
+
+func (l *Loader[T]) notFoundTTL() time.Duration {
+	if l.NotFoundTTL == 0 {
+		return DefaultNotFoundTTL
+	}
+	return l.NotFoundTTL
+}
+
+// jitter 在 ttl 的基础上随机增减 Jitter 比例
+func (l *Loader[T]) jitter(ttl time.Duration) time.Duration {
+	ratio := l.Jitter
+	if ratio == 0 {
+		ratio = DefaultJitter
+	}
+	if ratio < 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := time.Duration(float64(ttl) * ratio * (rand.Float64()*2 - 1))
+	if ttl+delta <= 0 {
+		return ttl
+	}
+	return ttl + delta
+}
+
+// LoadStats Loader 的命中统计
+type LoadStats struct {
+	Hits         uint64 `json:"hits"`
+	Misses       uint64 `json:"misses"`
+	NegativeHits uint64 `json:"negativeHits"`
+	LoadErrors   uint64 `json:"loadErrors"`
+}
+
+type loadCounter struct {
+	hits, misses, negativeHits, loadErrors atomic.Uint64
+}
+
+var loadCounters sync.Map
+
+func loaderStats(name string) *loadCounter {
+	if name == "" {
+		name = "default"
+	}
+	if counter, ok := loadCounters.Load(name); ok {
+		return counter.(*loadCounter)
+	}
+	counter, _ := loadCounters.LoadOrStore(name, &loadCounter{})
+	return counter.(*loadCounter)
+}
+
+// Stats 返回各 Loader 的命中统计，key 为 Loader.Name
+func Stats() map[string]LoadStats {
+	result := make(map[string]LoadStats)
+	loadCounters.Range(func(key, value any) bool {
+		counter := value.(*loadCounter)
+		result[key.(string)] = LoadStats{
+			Hits:         counter.hits.Load(),
+			Misses:       counter.misses.Load(),
+			NegativeHits: counter.negativeHits.Load(),
+			LoadErrors:   counter.loadErrors.Load(),
+		}
+		return true
+	})
+	return result
+}
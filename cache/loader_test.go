@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zhangc-zwl/thunder/gorms"
+)
+
+func TestGetOrLoadCoalescesAndCaches(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory(0)
+	var calls atomic.Int32
+	load := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "value", nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := GetOrLoad(ctx, store, "k", time.Minute, load); err != nil || v != "value" {
+				t.Errorf("got %q %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := GetOrLoad(ctx, store, "k", time.Minute, load); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("loader called %d times", calls.Load())
+	}
+}
+
+func TestLoaderCachesNotFound(t *testing.T) {
+	ctx := context.Background()
+	l := &Loader[int]{Name: "notFound", Cache: NewMemory(0), TTL: time.Minute}
+	var calls atomic.Int32
+	load := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, gorm.ErrRecordNotFound
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := l.Get(ctx, "missing", load); !gorms.IsRecordNotFoundError(err) {
+			t.Fatalf("want record not found, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("loader called %d times", calls.Load())
+	}
+	stats := Stats()["notFound"]
+	if stats.Misses != 1 || stats.NegativeHits != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLoaderDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	l := &Loader[int]{Cache: NewMemory(0), TTL: time.Minute}
+	boom := errors.New("boom")
+	if _, err := l.Get(ctx, "err", func(ctx context.Context) (int, error) { return 0, boom }); !errors.Is(err, boom) {
+		t.Fatalf("want boom, got %v", err)
+	}
+	if v, err := l.Get(ctx, "err", func(ctx context.Context) (int, error) { return 7, nil }); err != nil || v != 7 {
+		t.Fatalf("got %d %v", v, err)
+	}
+}
+
+func TestLoaderDecodeFailure(t *testing.T) {
+	ctx := context.Background()
+	// 未调用 logs.Init 时日志经由 slog 的默认 logger 写入标准库 log
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	store := NewMemory(0)
+	_ = store.Set(ctx, "k", []byte("not json"), time.Minute)
+	l := &Loader[int]{Cache: store, TTL: time.Minute}
+	// 无法解析的缓存当作未命中，回源后覆盖
+	if v, err := l.Get(ctx, "k", func(ctx context.Context) (int, error) { return 7, nil }); err != nil || v != 7 {
+		t.Fatalf("got %d %v", v, err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "cache value decode failed") || strings.Contains(out, "error=<nil>") || !strings.Contains(out, "invalid character") {
+		t.Fatalf("decode error should be logged, got %q", out)
+	}
+	if v, err := l.Get(ctx, "k", func(ctx context.Context) (int, error) { return 0, errors.New("should hit") }); err != nil || v != 7 {
+		t.Fatalf("reloaded value should be cached, got %d %v", v, err)
+	}
+}
+
+func TestLoaderJitter(t *testing.T) {
+	l := &Loader[int]{Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		ttl := l.jitter(time.Minute)
+		if ttl < 48*time.Second || ttl > 72*time.Second {
+			t.Fatalf("ttl %v out of range", ttl)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+var (
+	// ErrNotObtained 在 ctx 结束前没有获取到锁
+	ErrNotObtained = errors.New("cache: lock not obtained")
+	// ErrLockNotHeld 锁已过期或被其他持有者获取
+	ErrLockNotHeld = errors.New("cache: lock not held")
+)
+
+const (
+	DefaultRetryMin = 50 * time.Millisecond
+	DefaultRetryMax = time.Second
+)
+
+// Locker 互斥锁，Redis 实现用于多实例之间，Memory 实现用于测试和单机部署
+type Locker interface {
+	// Obtain 获取锁，被占用时按退避策略重试直到 ctx 结束，超时返回 ErrNotObtained
+	// 持有期间会自动续期，使用完必须调用 Release
+	Obtain(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+	// TryObtain 只尝试一次，被占用时立即返回 ErrNotObtained
+	TryObtain(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+}
+
+// lockBackend 锁的存储，所有操作都需要校验持有者 token
+type lockBackend interface {
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	release(ctx context.Context, key, token string) (bool, error)
+}
+
+type locker struct {
+	backend  lockBackend
+	retryMin time.Duration
+	retryMax time.Duration
+}
+
+func (l *locker) Obtain(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	token := uuid.NewString()
+	backoff := l.retryMin
+	for {
+		ok, err := l.backend.acquire(ctx, key, token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return newLock(l.backend, key, token, ttl), nil
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ErrNotObtained
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > l.retryMax {
+			backoff = l.retryMax
+		}
+	}
+}
+
+func (l *locker) TryObtain(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	token := uuid.NewString()
+	ok, err := l.backend.acquire(ctx, key, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotObtained
+	}
+	return newLock(l.backend, key, token, ttl), nil
+}
+
+// SetBackoff 设置获取锁失败后的重试间隔，从 min 开始每次翻倍，最大为 max
+func (l *locker) SetBackoff(min, max time.Duration) {
+	l.retryMin = min
+	l.retryMax = max
+}
+
+// Lock 已获取的锁，持有期间每 ttl/3 自动续期一次
+type Lock struct {
+	backend lockBackend
+	key     string
+	token   string
+	ttl     time.Duration
+	stop    chan struct{}
+	lost    chan struct{}
+	once    sync.Once
+}
+
+func newLock(backend lockBackend, key, token string, ttl time.Duration) *Lock {
+	lock := &Lock{
+		backend: backend,
+		key:     key,
+		token:   token,
+		ttl:     ttl,
+		stop:    make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+	go lock.keepAlive()
+	return lock
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 持有者的唯一标识
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Lost 续期失败时关闭，说明锁可能已被其他持有者获取，临界区内的长任务应停止
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 手动续期
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ok, err := l.backend.refresh(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 释放锁，只有持有者才能删除，锁已过期时返回 ErrLockNotHeld
+func (l *Lock) Release(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	ok, err := l.backend.release(ctx, l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) keepAlive() {
+	interval := l.ttl / 3
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := l.Refresh(ctx, l.ttl)
+			cancel()
+			if errors.Is(err, ErrLockNotHeld) {
+				logs.Warn("cache lock lost", "key", l.key)
+				close(l.lost)
+				return
+			}
+			if err != nil {
+				// 网络抖动时继续尝试，锁在 ttl 内仍然有效
+				logs.Warn("cache lock refresh failed", "key", l.key, "error", err)
+			}
+		}
+	}
+}
+
+// WithLock 获取锁后执行 fn，结束后释放
+// 续期失败时会取消传给 fn 的 ctx，context.Cause 返回 ErrLockNotHeld，fn 应尽快停止临界区内的操作
+//
+//	err := cache.WithLock(ctx, locker, "order:"+id, 10*time.Second, func(ctx context.Context) error {
+//		return updateOrderState(ctx, id)
+//	})
+func WithLock(ctx context.Context, l Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := l.Obtain(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			logs.CtxWarn(ctx, "cache lock release failed", "key", key, "error", err)
+		}
+	}()
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel(ErrLockNotHeld)
+		case <-fnCtx.Done():
+		}
+	}()
+	return fn(fnCtx)
+}
+
+var (
+	// 只有 token 一致时才续期，避免延长其他持有者的锁
+	refreshLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+	// 只有 token 一致时才删除，避免锁过期后误删其他持有者的锁
+	releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+// RedisLocker 基于 Redis 的分布式锁
+type RedisLocker struct {
+	locker
+}
+
+type redisLockBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisLocker 创建分布式锁，prefix 为空时使用 "LOCK:"
+func NewRedisLocker(client redis.UniversalClient, prefix string) *RedisLocker {
+	if prefix == "" {
+		prefix = "LOCK:"
+	}
+	return &RedisLocker{locker{
+		backend:  &redisLockBackend{client: client, prefix: prefix},
+		retryMin: DefaultRetryMin,
+		retryMax: DefaultRetryMax,
+	}}
+}
+
+func (b *redisLockBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return b.client.SetNX(ctx, b.prefix+key, token, ttl).Result()
+}
+
+func (b *redisLockBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshLockScript.Run(ctx, b.client, []string{b.prefix + key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+func (b *redisLockBackend) release(ctx context.Context, key, token string) (bool, error) {
+	n, err := releaseLockScript.Run(ctx, b.client, []string{b.prefix + key}, token).Int64()
+	return n == 1, err
+}
+
+// MemoryLocker 进程内的锁，语义与 RedisLocker 一致
+type MemoryLocker struct {
+	locker
+}
+
+type memoryLockBackend struct {
+	mu    sync.Mutex
+	locks map[string]memoryLockItem
+}
+
+type memoryLockItem struct {
+	token    string
+	expireAt time.Time
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locker{
+		backend:  &memoryLockBackend{locks: make(map[string]memoryLockItem)},
+		retryMin: DefaultRetryMin,
+		retryMax: DefaultRetryMax,
+	}}
+}
+
+func (b *memoryLockBackend) acquire(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.held(key); ok {
+		return false, nil
+	}
+	b.locks[key] = memoryLockItem{token: token, expireAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryLockBackend) refresh(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	item, ok := b.held(key)
+	if !ok || item.token != token {
+		return false, nil
+	}
+	item.expireAt = time.Now().Add(ttl)
+	b.locks[key] = item
+	return true, nil
+}
+
+func (b *memoryLockBackend) release(_ context.Context, key, token string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	item, ok := b.held(key)
+	if !ok || item.token != token {
+		return false, nil
+	}
+	delete(b.locks, key)
+	return true, nil
+}
+
+func (b *memoryLockBackend) held(key string) (memoryLockItem, bool) {
+	item, ok := b.locks[key]
+	if !ok {
+		return item, false
+	}
+	if time.Now().After(item.expireAt) {
+		delete(b.locks, key)
+		return item, false
+	}
+	return item, true
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestMemoryLockerMutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+	locker.SetBackoff(time.Millisecond, 5*time.Millisecond)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		counter int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(ctx, locker, "k", time.Second, func(ctx context.Context) error {
+				mu.Lock()
+				holders++
+				if holders > 1 {
+					t.Error("lock held by more than one owner")
+				}
+				mu.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				mu.Lock()
+				holders--
+				counter++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 10 {
+		t.Fatalf("counter want 10, got %d", counter)
+	}
+}
+
+func TestLockTimeoutAndOwnership(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+	lock, err := locker.TryObtain(ctx, "k", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locker.TryObtain(ctx, "k", time.Second); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("want ErrNotObtained, got %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Obtain(timeout, "k", time.Second); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("want ErrNotObtained, got %v", err)
+	}
+	// 自动续期使锁在超过 ttl 后仍然有效
+	time.Sleep(60 * time.Millisecond)
+	if _, err := locker.TryObtain(ctx, "k", time.Second); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("lock should be extended, got %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("want ErrLockNotHeld, got %v", err)
+	}
+	other, err := locker.TryObtain(ctx, "k", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = other.Release(ctx)
+}
+
+func TestWithLockCancelsOnLost(t *testing.T) {
+	locker := NewMemoryLocker()
+	backend := locker.backend.(*memoryLockBackend)
+	err := WithLock(context.Background(), locker, "k", 30*time.Millisecond, func(ctx context.Context) error {
+		// 模拟锁过期后被其他持有者获取，下一次续期失败
+		backend.mu.Lock()
+		backend.locks["k"] = memoryLockItem{token: "other", expireAt: time.Now().Add(time.Second)}
+		backend.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(time.Second):
+			return errors.New("ctx should be cancelled when the lock is lost")
+		}
+	})
+	if !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("want ErrLockNotHeld, got %v", err)
+	}
+}
+
+func TestRedisLockerMutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	// 不同的客户端模拟多个实例
+	lockers := []*RedisLocker{
+		NewRedisLocker(newTestRedisClient(t, mr), ""),
+		NewRedisLocker(newTestRedisClient(t, mr), ""),
+	}
+	for _, locker := range lockers {
+		locker.SetBackoff(time.Millisecond, 5*time.Millisecond)
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		counter int
+	)
+	for i := 0; i < 10; i++ {
+		locker := lockers[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(ctx, locker, "k", time.Second, func(ctx context.Context) error {
+				mu.Lock()
+				holders++
+				if holders > 1 {
+					t.Error("lock held by more than one owner")
+				}
+				mu.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				mu.Lock()
+				holders--
+				counter++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 10 {
+		t.Fatalf("counter want 10, got %d", counter)
+	}
+	if mr.Exists("LOCK:k") {
+		t.Fatal("lock should be released")
+	}
+}
+
+func TestRedisLockOwnership(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	locker := NewRedisLocker(newTestRedisClient(t, mr), "")
+	lock, err := locker.TryObtain(ctx, "k", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release(ctx)
+	if value, _ := mr.Get("LOCK:k"); value != lock.Token() {
+		t.Fatalf("lock value should be the token, got %q", value)
+	}
+	if _, err := locker.TryObtain(ctx, "k", time.Minute); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("want ErrNotObtained, got %v", err)
+	}
+	// 其他持有者的 token 既不能续期也不能释放
+	foreign := newLock(locker.backend, "k", "other", 0)
+	if err := foreign.Refresh(ctx, time.Hour); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("refresh with a foreign token: want ErrLockNotHeld, got %v", err)
+	}
+	if err := foreign.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("release with a foreign token: want ErrLockNotHeld, got %v", err)
+	}
+	if value, _ := mr.Get("LOCK:k"); value != lock.Token() || mr.TTL("LOCK:k") != time.Minute {
+		t.Fatalf("lock should be untouched, got %q %v", value, mr.TTL("LOCK:k"))
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("want ErrLockNotHeld, got %v", err)
+	}
+}
+
+func TestRedisLockExtend(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	locker := NewRedisLocker(newTestRedisClient(t, mr), "")
+	lock, err := locker.TryObtain(ctx, "k", 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release(ctx)
+	if err := lock.Refresh(ctx, time.Minute); err != nil || mr.TTL("LOCK:k") != time.Minute {
+		t.Fatalf("manual refresh: ttl %v %v", mr.TTL("LOCK:k"), err)
+	}
+	// 后台续期把过期时间重新设置为获取时的 ttl
+	waitFor(t, func() bool { return mr.TTL("LOCK:k") == 300*time.Millisecond })
+	mr.FastForward(250 * time.Millisecond)
+	waitFor(t, func() bool { return mr.TTL("LOCK:k") == 300*time.Millisecond })
+	// 锁被其他持有者获取后续期失败，Lost 被关闭
+	mr.Set("LOCK:k", "other")
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lost should be closed when the lock is taken over")
+	}
+	if value, _ := mr.Get("LOCK:k"); value != "other" {
+		t.Fatalf("lost lock must not touch the new owner, got %q", value)
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory 进程内的 LRU 缓存，超过容量时淘汰最久未使用的 key，过期的 key 在访问时清除
+// 写入和读取时都会复制 value，调用方修改传入或返回的切片不会影响缓存中的数据
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type memoryItem struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// NewMemory 创建 LRU 缓存，capacity <= 0 时不限制容量
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(item.value), nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, bytes.Clone(value), ttl)
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MGet(_ context.Context, keys ...string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if item, ok := m.get(key); ok {
+			result[key] = bytes.Clone(item.value)
+		}
+	}
+	return result, nil
+}
+
+// Incr 与 Redis 一致，以十进制字符串保存整数，自增不会改变原有的过期时间
+func (m *Memory) Incr(_ context.Context, key string, delta int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var (
+		current int64
+		ttl     time.Duration
+	)
+	if item, ok := m.get(key); ok {
+		n, err := strconv.ParseInt(string(item.value), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		current = n
+		if !item.expireAt.IsZero() {
+			ttl = time.Until(item.expireAt)
+		}
+	}
+	current += delta
+	m.set(key, []byte(strconv.FormatInt(current, 10)), ttl)
+	return current, nil
+}
+
+// Len 当前缓存的 key 数量，包含尚未清除的过期 key
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+func (m *Memory) get(key string) (*memoryItem, bool) {
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*memoryItem)
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		m.remove(el)
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	return item, true
+}
+
+func (m *Memory) set(key string, value []byte, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	if el, ok := m.items[key]; ok {
+		item := el.Value.(*memoryItem)
+		item.value = value
+		item.expireAt = expireAt
+		m.ll.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.ll.PushFront(&memoryItem{key: key, value: value, expireAt: expireAt})
+	if m.capacity > 0 && m.ll.Len() > m.capacity {
+		m.remove(m.ll.Back())
+	}
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*memoryItem).key)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+	_ = m.Set(ctx, "a", []byte("1"), 0)
+	_ = m.Set(ctx, "b", []byte("2"), 0)
+	// 访问 a 后 b 成为最久未使用的 key
+	if _, err := m.Get(ctx, "a"); err != nil {
+		t.Fatalf("get a: %v", err)
+	}
+	_ = m.Set(ctx, "c", []byte("3"), 0)
+	if _, err := m.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("b should be evicted, got %v", err)
+	}
+	values, _ := m.MGet(ctx, "a", "b", "c")
+	if len(values) != 2 || string(values["a"]) != "1" || string(values["c"]) != "3" {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
+
+func TestMemoryExpireAndIncr(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(0)
+	_ = m.Set(ctx, "k", []byte("v"), 20*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if _, err := m.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("k should be expired, got %v", err)
+	}
+	if n, _ := m.Incr(ctx, "n", 2); n != 2 {
+		t.Fatalf("incr want 2, got %d", n)
+	}
+	if n, _ := m.Incr(ctx, "n", -5); n != -3 {
+		t.Fatalf("incr want -3, got %d", n)
+	}
+}
+
+func TestMemoryCopiesValues(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(0)
+	value := []byte("abc")
+	_ = m.Set(ctx, "k", value, 0)
+	// 修改写入的切片不影响缓存
+	value[0] = 'x'
+	got, _ := m.Get(ctx, "k")
+	if string(got) != "abc" {
+		t.Fatalf("stored value changed with the caller's slice: %q", got)
+	}
+	// 修改读取到的切片也不影响缓存
+	got[0] = 'y'
+	values, _ := m.MGet(ctx, "k")
+	values["k"][1] = 'z'
+	if got, _ := m.Get(ctx, "k"); string(got) != "abc" {
+		t.Fatalf("stored value changed with the returned slice: %q", got)
+	}
+}
+
+func TestTypedCodecs(t *testing.T) {
+	type user struct {
+		Id   int64
+		Name string
+	}
+	ctx := context.Background()
+	for _, codec := range []Codec{JSON, Gob, Msgpack} {
+		users := NewTyped[user](NewMemory(10), codec)
+		if err := users.Set(ctx, "u", user{Id: 1, Name: "tom"}, time.Minute); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+		got, err := users.Get(ctx, "u")
+		if err != nil || got.Id != 1 || got.Name != "tom" {
+			t.Fatalf("get: %+v %v", got, err)
+		}
+	}
+}
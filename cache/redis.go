@@ -9,7 +9,7 @@ import (
 )
 
 type RedisCache struct {
-	redisCli *redis.Client
+	redisCli redis.UniversalClient
 }
 
 func (c *RedisCache) Get(key string) (string, error) {
@@ -56,12 +56,27 @@ func (c *RedisCache) Set(key string, value string, expire int64) error {
 	return c.redisCli.Set(context.Background(), key, value, time.Duration(expire)*time.Second).Err()
 }
 
+// SetNX key 不存在时才写入，返回是否写入成功
+func (c *RedisCache) SetNX(ctx context.Context, key string, value string, expire time.Duration) (bool, error) {
+	return c.redisCli.SetNX(ctx, key, value, expire).Result()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	return c.redisCli.Del(ctx, keys...).Err()
+}
+
+// DeleteIfEqual 只有当前值等于 value 时才删除，返回是否删除
+func (c *RedisCache) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	n, err := releaseLockScript.Run(ctx, c.redisCli, []string{key}, value).Int64()
+	return n == 1, err
+}
+
 func (c *RedisCache) Exist(key string) bool {
 	result, err := c.redisCli.Exists(context.Background(), key).Result()
 	return result == 1 && err == nil
 }
 func NewRedisCache() *RedisCache {
 	return &RedisCache{
-		redisCli: database.RedisCli.Client,
+		redisCli: database.GetRedis().Client,
 	}
 }
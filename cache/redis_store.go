@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore 基于 Redis 的 Cache 实现，所有操作都使用调用方传入的 context
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 创建 RedisStore，prefix 会拼接在所有 key 之前，可以为空
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+func (r *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	// 逐个删除，兼容 Redis Cluster 下 key 分布在不同 slot 的情况
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, r.prefix+key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) MGet(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	result, _, err := r.mget(ctx, false, keys...)
+	return result, err
+}
+
+// getWithTTL 读取 key 及其在 Redis 中的剩余过期时间，没有过期时间时返回的 ttl 不大于 0
+func (r *RedisStore) getWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	values, ttls, err := r.mget(ctx, true, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return value, ttls[key], nil
+}
+
+// mget 批量读取，withTTL 为 true 时同一个 pipeline 中一起读取剩余过期时间，供 TwoLevel 回填 L1 时使用
+func (r *RedisStore) mget(ctx context.Context, withTTL bool, keys ...string) (map[string][]byte, map[string]time.Duration, error) {
+	result := make(map[string][]byte, len(keys))
+	ttls := make(map[string]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return result, ttls, nil
+	}
+	cmds := make([]*redis.StringCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, r.prefix+key)
+			if withTTL {
+				ttlCmds[i] = pipe.PTTL(ctx, r.prefix+key)
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, err
+	}
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, nil, err
+		}
+		result[keys[i]] = value
+		if withTTL {
+			ttls[keys[i]] = ttlCmds[i].Val()
+		}
+	}
+	return result, ttls, nil
+}
+
+func (r *RedisStore) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	return r.client.IncrBy(ctx, r.prefix+key, delta).Result()
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisClient(t *testing.T, mr *miniredis.Miniredis) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	s := NewRedisStore(newTestRedisClient(t, mr), "app:")
+	if _, err := s.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	if err := s.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Set(ctx, "b", []byte("2"), 0)
+	if value, _ := mr.Get("app:a"); value != "1" || mr.TTL("app:a") != time.Minute {
+		t.Fatalf("key should be prefixed with ttl, got %q %v", value, mr.TTL("app:a"))
+	}
+	values, err := s.MGet(ctx, "a", "b", "missing")
+	if err != nil || len(values) != 2 || string(values["a"]) != "1" || string(values["b"]) != "2" {
+		t.Fatalf("unexpected values %v %v", values, err)
+	}
+	value, ttl, err := s.getWithTTL(ctx, "a")
+	if err != nil || string(value) != "1" || ttl != time.Minute {
+		t.Fatalf("getWithTTL = %q %v %v", value, ttl, err)
+	}
+	if _, ttl, _ := s.getWithTTL(ctx, "b"); ttl > 0 {
+		t.Fatalf("key without expire should have no ttl, got %v", ttl)
+	}
+	if n, err := s.Incr(ctx, "n", 3); err != nil || n != 3 {
+		t.Fatalf("incr = %d %v", n, err)
+	}
+	if err := s.Delete(ctx, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("app:a") || mr.Exists("app:b") {
+		t.Fatal("keys should be deleted")
+	}
+}
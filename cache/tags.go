@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/database"
+)
+
+// TagPrefix 标签索引的 key 前缀，每个标签对应一个保存缓存 key 的集合
+const TagPrefix = "CACHE_TAG:"
+
+// 将 key 加入标签集合，集合的过期时间只延长不缩短，保证集合不会早于其中的缓存过期
+var addTagScript = redis.NewScript(`
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if redis.call('PTTL', KEYS[1]) < ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// AddTags 为缓存 key 打上标签，expire 为该缓存的过期时间
+func (c *RedisCache) AddTags(ctx context.Context, key string, expire time.Duration, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := c.redisCli.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			// 管道中无法处理 NOSCRIPT 错误后的重试，因此直接使用 EVAL
+			addTagScript.Eval(ctx, pipe, []string{TagPrefix + tag}, key, expire.Milliseconds())
+		}
+		return nil
+	})
+	return err
+}
+
+// 原子地取出并删除标签集合，取出之后新加入标签的 key 会进入新的集合，不会在删除集合时丢失
+var popTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`)
+
+// InvalidateTags 删除带有任一标签的全部缓存
+// 缓存保存在 Redis 中，删除后所有实例都会重新回源
+func (c *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := popTagScript.Run(ctx, c.redisCli, []string{TagPrefix + tag}).StringSlice()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		// 逐个删除而不是一次 DEL 多个 key，兼容 Redis Cluster 下 key 分布在不同 slot 的情况
+		_, err = c.redisCli.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateTags 使用全局 Redis 删除带有任一标签的全部缓存，一般在写操作完成后调用，
+// Redis 尚未连接时返回 database.ErrNotConnected
+//
+//	cache.InvalidateTags(ctx, "article:"+id, "article:list")
+func InvalidateTags(ctx context.Context, tags ...string) error {
+	if database.GetRedis() == nil {
+		return database.ErrNotConnected
+	}
+	return NewRedisCache().InvalidateTags(ctx, tags...)
+}
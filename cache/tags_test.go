@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/database"
+)
+
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return &RedisCache{redisCli: client}, mr
+}
+
+func TestAddTagsExtendsExpire(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedisCache(t)
+	if err := c.AddTags(ctx, "k1", time.Minute, "article:1", "article:list"); err != nil {
+		t.Fatal(err)
+	}
+	// 较短的过期时间不会缩短集合的过期时间
+	if err := c.AddTags(ctx, "k2", time.Second, "article:list"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(TagPrefix + "article:list"); ttl != time.Minute {
+		t.Fatalf("tag ttl should stay 1m, got %v", ttl)
+	}
+	members, _ := mr.Members(TagPrefix + "article:list")
+	if len(members) != 2 {
+		t.Fatalf("unexpected members %v", members)
+	}
+}
+
+func TestInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedisCache(t)
+	for _, key := range []string{"k1", "k2", "k3"} {
+		_ = mr.Set(key, "v")
+	}
+	_ = c.AddTags(ctx, "k1", time.Minute, "article:1", "article:list")
+	_ = c.AddTags(ctx, "k2", time.Minute, "article:list")
+	_ = c.AddTags(ctx, "k3", time.Minute, "user:1")
+
+	if err := c.InvalidateTags(ctx, "article:list", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("k1") || mr.Exists("k2") || mr.Exists(TagPrefix+"article:list") {
+		t.Fatal("tagged keys and the tag set should be deleted")
+	}
+	if !mr.Exists("k3") || !mr.Exists(TagPrefix+"user:1") {
+		t.Fatal("keys with other tags should be kept")
+	}
+
+	// 失效之后重新打标签的 key 进入新的集合，下次失效时仍能删除
+	_ = mr.Set("k1", "v")
+	_ = c.AddTags(ctx, "k1", time.Minute, "article:list")
+	if err := c.InvalidateTags(ctx, "article:list"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("k1") {
+		t.Fatal("key tagged after invalidation should be deleted by the next invalidation")
+	}
+}
+
+func TestInvalidateTagsWithoutRedis(t *testing.T) {
+	old := database.GetRedis()
+	database.SetRedis(nil)
+	t.Cleanup(func() { database.SetRedis(old) })
+	if err := InvalidateTags(context.Background(), "article:1"); !errors.Is(err, database.ErrNotConnected) {
+		t.Fatalf("want ErrNotConnected, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+// DefaultInvalidateChannel 两级缓存广播失效消息的默认频道
+const DefaultInvalidateChannel = "CACHE_INVALIDATE"
+
+// TwoLevel 两级缓存，L1 为进程内 LRU，L2 为 Redis
+// 写入和删除时通过 Redis pub/sub 通知其他实例清除各自的 L1，L1 的过期时间应远小于 L2 以兜底丢失的消息
+type TwoLevel struct {
+	local    *Memory
+	remote   *RedisStore
+	client   redis.UniversalClient
+	channel  string
+	localTTL time.Duration
+	id       string
+	pubsub   *redis.PubSub
+	once     sync.Once
+}
+
+type invalidateMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// NewTwoLevel 创建两级缓存并订阅失效频道，不再使用时调用 Close
+// capacity 为 L1 的容量，localTTL 为 L1 的最长缓存时间，channel 为空时使用 DefaultInvalidateChannel
+func NewTwoLevel(client redis.UniversalClient, prefix string, capacity int, localTTL time.Duration, channel string) *TwoLevel {
+	if channel == "" {
+		channel = DefaultInvalidateChannel
+	}
+	t := &TwoLevel{
+		local:    NewMemory(capacity),
+		remote:   NewRedisStore(client, prefix),
+		client:   client,
+		channel:  channel,
+		localTTL: localTTL,
+		id:       uuid.NewString(),
+	}
+	t.pubsub = client.Subscribe(context.Background(), channel)
+	go t.listen()
+	return t
+}
+
+func (t *TwoLevel) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := t.local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, ttl, err := t.remote.getWithTTL(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = t.local.Set(ctx, key, value, t.ttl(ttl))
+	return value, nil
+}
+
+func (t *TwoLevel) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	_ = t.local.Set(ctx, key, value, t.ttl(ttl))
+	t.publish(ctx, key)
+	return nil
+}
+
+func (t *TwoLevel) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_ = t.local.Delete(ctx, keys...)
+	if err := t.remote.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	t.publish(ctx, keys...)
+	return nil
+}
+
+func (t *TwoLevel) MGet(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	result, _ := t.local.MGet(ctx, keys...)
+	var missing []string
+	for _, key := range keys {
+		if _, ok := result[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+	values, ttls, err := t.remote.mget(ctx, true, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		result[key] = value
+		_ = t.local.Set(ctx, key, value, t.ttl(ttls[key]))
+	}
+	return result, nil
+}
+
+// Incr 计数器直接在 Redis 上自增，同时清除各实例的 L1
+func (t *TwoLevel) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	n, err := t.remote.Incr(ctx, key, delta)
+	if err != nil {
+		return 0, err
+	}
+	_ = t.local.Delete(ctx, key)
+	t.publish(ctx, key)
+	return n, nil
+}
+
+// Close 取消订阅失效频道
+func (t *TwoLevel) Close() error {
+	var err error
+	t.once.Do(func() {
+		err = t.pubsub.Close()
+	})
+	return err
+}
+
+// L1 的过期时间不超过 L2 的剩余时间，ttl 不大于 0 表示 L2 没有过期时间
+func (t *TwoLevel) ttl(ttl time.Duration) time.Duration {
+	if ttl > 0 && (t.localTTL <= 0 || ttl < t.localTTL) {
+		return ttl
+	}
+	return t.localTTL
+}
+
+func (t *TwoLevel) publish(ctx context.Context, keys ...string) {
+	payload, _ := json.Marshal(invalidateMessage{Origin: t.id, Keys: keys})
+	if err := t.client.Publish(ctx, t.channel, payload).Err(); err != nil {
+		logs.CtxWarn(ctx, "cache invalidate publish failed", "channel", t.channel, "error", err)
+	}
+}
+
+func (t *TwoLevel) listen() {
+	ctx := context.Background()
+	for msg := range t.pubsub.Channel() {
+		var m invalidateMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+			logs.CtxWarn(ctx, "cache invalidate message invalid", "payload", msg.Payload, "error", err)
+			continue
+		}
+		// 自己发出的消息在本地已经处理过
+		if m.Origin == t.id {
+			continue
+		}
+		_ = t.local.Delete(ctx, m.Keys...)
+	}
+}
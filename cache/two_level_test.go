@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestTwoLevels 创建共享同一个 Redis 和失效频道的多个实例，模拟多个进程
+func newTestTwoLevels(t *testing.T, mr *miniredis.Miniredis, n int, localTTL time.Duration) []*TwoLevel {
+	t.Helper()
+	levels := make([]*TwoLevel, n)
+	for i := range levels {
+		levels[i] = NewTwoLevel(newTestRedisClient(t, mr), "app:", 100, localTTL, "")
+		l := levels[i]
+		t.Cleanup(func() { _ = l.Close() })
+	}
+	waitFor(t, func() bool { return mr.PubSubNumSub(DefaultInvalidateChannel)[DefaultInvalidateChannel] == n })
+	return levels
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func inLocal(l *TwoLevel, key string) bool {
+	_, err := l.local.Get(context.Background(), key)
+	return err == nil
+}
+
+// localTTL 返回 L1 中 key 的剩余过期时间
+func localTTL(l *TwoLevel, key string) time.Duration {
+	l.local.mu.Lock()
+	defer l.local.mu.Unlock()
+	return time.Until(l.local.items[key].Value.(*memoryItem).expireAt)
+}
+
+func TestTwoLevelInvalidatesOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	levels := newTestTwoLevels(t, mr, 2, time.Minute)
+	a, b := levels[0], levels[1]
+
+	if err := a.Set(ctx, "k", []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := b.Get(ctx, "k"); err != nil || string(value) != "v1" {
+		t.Fatalf("b get = %q %v", value, err)
+	}
+	if !inLocal(b, "k") {
+		t.Fatal("get should fill L1")
+	}
+	// a 更新后 b 的 L1 被清除，下次读取拿到新值
+	_ = a.Set(ctx, "k", []byte("v2"), time.Hour)
+	waitFor(t, func() bool { return !inLocal(b, "k") })
+	if value, _ := b.Get(ctx, "k"); string(value) != "v2" {
+		t.Fatalf("b should read the new value, got %q", value)
+	}
+
+	// 同一个频道上的消息按顺序投递：a 收到 b 之后发出的消息时，一定已经处理过自己发出的消息
+	_ = a.Set(ctx, "mark", []byte("1"), time.Hour)
+	_ = b.Delete(ctx, "mark")
+	waitFor(t, func() bool { return !inLocal(a, "mark") })
+	if value, err := a.local.Get(ctx, "k"); err != nil || string(value) != "v2" {
+		t.Fatalf("a should keep its own write in L1, got %q %v", value, err)
+	}
+
+	// 删除同样清除其他实例的 L1
+	_ = a.Delete(ctx, "k")
+	waitFor(t, func() bool { return !inLocal(b, "k") })
+	if _, err := b.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestTwoLevelLocalTTL(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	levels := newTestTwoLevels(t, mr, 2, time.Minute)
+	a, b := levels[0], levels[1]
+
+	_ = a.Set(ctx, "short", []byte("1"), 2*time.Second)
+	_ = a.Set(ctx, "long", []byte("2"), time.Hour)
+	_ = a.Set(ctx, "forever", []byte("3"), 0)
+	if ttl := localTTL(a, "short"); ttl <= 0 || ttl > 2*time.Second {
+		t.Fatalf("set should clamp L1 to the L2 ttl, got %v", ttl)
+	}
+	// 回填 L1 时按 L2 的剩余时间限制
+	_ = a.Set(ctx, "short", []byte("1"), 10*time.Second)
+	mr.FastForward(8 * time.Second)
+	if _, err := b.Get(ctx, "short"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := localTTL(b, "short"); ttl <= 0 || ttl > 2*time.Second {
+		t.Fatalf("get should clamp L1 to the remaining L2 ttl, got %v", ttl)
+	}
+	values, err := b.MGet(ctx, "long", "forever", "missing")
+	if err != nil || len(values) != 2 {
+		t.Fatalf("mget = %v %v", values, err)
+	}
+	if ttl := localTTL(b, "long"); ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("L1 ttl should not exceed localTTL, got %v", ttl)
+	}
+	if ttl := localTTL(b, "forever"); ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("key without expire should use localTTL, got %v", ttl)
+	}
+	// long 已经过去 8 秒，再前进到只剩 3 秒
+	mr.FastForward(time.Hour - 11*time.Second)
+	_ = b.local.Delete(ctx, "long")
+	if _, err := b.MGet(ctx, "long"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := localTTL(b, "long"); ttl <= 0 || ttl > 3*time.Second {
+		t.Fatalf("mget should clamp L1 to the remaining L2 ttl, got %v", ttl)
+	}
+}
@@ -0,0 +1,126 @@
+// thunder 命令行工具
+//
+//	thunder migrate [-c etc/config.yml] [--dir migrations] [--datasource name] up|down|status|create
+//
+// 迁移文件从磁盘目录读取，使用内嵌迁移文件的应用可以直接调用 migrate.Run
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/database"
+	"github.com/zhangc-zwl/thunder/migrate"
+	"gorm.io/gorm"
+)
+
+const usage = `usage: thunder migrate [flags] <command> [command flags]
+
+flags:
+  -c, --config string       path to the config file (default "etc/config.yml")
+      --dir string          migrations directory (default "migrations")
+      --datasource string   named datasource to migrate instead of db.mysql / db.postgres
+
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "migrate" {
+		_, _ = fmt.Fprint(os.Stderr, usage+migrate.Usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := runMigrate(ctx, os.Args[2:]); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runMigrate(ctx context.Context, args []string) error {
+	flags := pflag.NewFlagSet("thunder migrate", pflag.ContinueOnError)
+	// 第一个非选项参数之后的内容交给 migrate.Run 解析
+	flags.SetInterspersed(false)
+	configFile := flags.StringP("config", "c", "etc/config.yml", "path to the config file")
+	dir := flags.String("dir", "migrations", "migrations directory")
+	datasource := flags.String("datasource", "", "named datasource to migrate")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) > 0 && args[0] == "create" {
+		return migrate.Run(ctx, nil, *dir, args, os.Stdout)
+	}
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	gdb, closeDB, err := connect(ctx, conf.DB, *datasource)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	return migrate.Run(ctx, migrate.New(gdb, os.DirFS(*dir)), *dir, args, os.Stdout)
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	conf := &config.Config{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// connect 连接要迁移的数据库，迁移只需要主库，忽略从库配置
+func connect(ctx context.Context, conf *config.DB, datasource string) (*gorm.DB, func(), error) {
+	if conf == nil {
+		return nil, nil, errors.New("thunder: db is not configured")
+	}
+	policy := conf.GetRetry()
+	if datasource != "" {
+		for _, ds := range conf.Datasources {
+			if ds.GetName() != datasource {
+				continue
+			}
+			primary := *ds
+			primary.Replicas = nil
+			s, err := database.ConnectDatasource(ctx, &primary, policy)
+			if err != nil {
+				return nil, nil, err
+			}
+			return database.GetDatasource(datasource), func() { _ = s.Close() }, nil
+		}
+		return nil, nil, fmt.Errorf("thunder: datasource %q is not configured", datasource)
+	}
+	switch {
+	case conf.Mysql != nil:
+		primary := *conf.Mysql
+		primary.Replicas = nil
+		m, err := database.ConnectMysql(ctx, &primary, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		return m.GormDB, func() { _ = m.Close() }, nil
+	case conf.Postgres != nil:
+		primary := *conf.Postgres
+		primary.Replicas = nil
+		p, err := database.ConnectPostgres(ctx, &primary, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p.GormDB, func() { _ = p.Close() }, nil
+	default:
+		return nil, nil, errors.New("thunder: neither db.mysql nor db.postgres is configured")
+	}
+}
@@ -26,6 +26,42 @@ type Config struct {
 	Jwt    *Jwt       `mapstructure:"jwt"`
 	Email  *Email     `mapstructure:"email"`
 	Log    *LogConfig `mapstructure:"log"`
+	RateLimit *RateLimit `mapstructure:"rateLimit"`
+	Authz     *Authz     `mapstructure:"authz"`
+	Tenant    *Tenant    `mapstructure:"tenant"`
+	Crypto    *Crypto    `mapstructure:"crypto"`
+	// Middlewares 中间件管道配置，按 order 从小到大依次挂载
+	Middlewares []*Middleware `mapstructure:"middlewares"`
+}
+
+// Middleware 单个中间件的启用配置
+type Middleware struct {
+	Name     *string  `mapstructure:"name"`     //注册到 midd 中的中间件名称
+	Enable   *bool    `mapstructure:"enable"`   //是否启用，默认启用
+	Order    *int     `mapstructure:"order"`    //挂载顺序，越小越先执行
+	Paths    []string `mapstructure:"paths"`    //生效的路径，支持 ** 通配，为空时全局生效
+	Excludes []string `mapstructure:"excludes"` //排除的路径，支持 ** 通配
+}
+
+func (m *Middleware) GetName() string {
+	if m == nil || m.Name == nil {
+		return ""
+	}
+	return *m.Name
+}
+
+func (m *Middleware) GetEnable() bool {
+	if m == nil || m.Enable == nil {
+		return true
+	}
+	return *m.Enable
+}
+
+func (m *Middleware) GetOrder() int {
+	if m == nil || m.Order == nil {
+		return 0
+	}
+	return *m.Order
 }
 
 type Email struct {
@@ -88,9 +124,62 @@ func (e *Email) GetBaseURL() string {
 }
 
 type Jwt struct {
-	Secret  *string        `mapstructure:"secret"`
-	Expire  *time.Duration `mapstructure:"expire"`
-	Refresh *time.Duration `mapstructure:"refresh"`
+	Secret     *string        `mapstructure:"secret"`
+	Expire     *time.Duration `mapstructure:"expire"`
+	Refresh    *time.Duration `mapstructure:"refresh"`
+	Keys       []*JwtKey      `mapstructure:"keys"`       //非对称密钥，配置后优先于 secret 使用
+	SigningKid *string        `mapstructure:"signingKid"` //签名使用的 kid，默认使用第一个带私钥的 key
+	JwksPath   *string        `mapstructure:"jwksPath"`   //JWKS 公钥发布的路由，为空时不注册
+}
+
+// JwtKey 非对称签名密钥，只配置公钥时仅用于验签（例如轮换下线的旧密钥）
+type JwtKey struct {
+	Kid        *string `mapstructure:"kid"`
+	Algorithm  *string `mapstructure:"algorithm"`  //RS256 | RS384 | RS512 | PS256 | ES256 | ES384 | ES512 | EdDSA
+	PrivateKey *string `mapstructure:"privateKey"` //私钥 PEM 文件路径
+	PublicKey  *string `mapstructure:"publicKey"`  //公钥 PEM 文件路径，为空时从私钥推导
+}
+
+func (k *JwtKey) GetKid() string {
+	if k == nil || k.Kid == nil {
+		return ""
+	}
+	return *k.Kid
+}
+
+func (k *JwtKey) GetAlgorithm() string {
+	if k == nil || k.Algorithm == nil {
+		return "RS256"
+	}
+	return *k.Algorithm
+}
+
+func (k *JwtKey) GetPrivateKey() string {
+	if k == nil || k.PrivateKey == nil {
+		return ""
+	}
+	return *k.PrivateKey
+}
+
+func (k *JwtKey) GetPublicKey() string {
+	if k == nil || k.PublicKey == nil {
+		return ""
+	}
+	return *k.PublicKey
+}
+
+func (j *Jwt) GetSigningKid() string {
+	if j == nil || j.SigningKid == nil {
+		return ""
+	}
+	return *j.SigningKid
+}
+
+func (j *Jwt) GetJwksPath() string {
+	if j == nil || j.JwksPath == nil {
+		return ""
+	}
+	return *j.JwksPath
 }
 
 func (j *Jwt) GetSecret() string {
@@ -130,10 +219,67 @@ type WxPay struct {
 	MchKeyPath  *string `mapstructure:"mchKeyPath"`
 }
 
+
 type DB struct {
 	Redis    *Redis    `mapstructure:"redis"`
 	Mysql    *Mysql    `mapstructure:"mysql"`
 	Postgres *Postgres `mapstructure:"postgres"`
+	Retry    *Retry    `mapstructure:"retry"` //连接失败时的重试策略
+	// Degraded 为 true 时连接失败不影响启动，失败的存储在后台继续重连，可通过健康检查查看状态
+	Degraded *bool `mapstructure:"degraded"`
+	// Datasources 按名称访问的多个数据源，与 mysql、postgres 相互独立
+	Datasources []*Datasource `mapstructure:"datasources"`
+}
+
+// Datasource 命名数据源，配置了 replicas 时读请求路由到从库，写请求和事务使用主库
+
+type Datasource struct {
+	Name         *string        `mapstructure:"name"`
+	Driver       *string        `mapstructure:"driver"` //mysql 或 postgres，默认 mysql
+	Host         *string        `mapstructure:"host"`
+	Port         *int           `mapstructure:"port"`
+	User         *string        `mapstructure:"user"`
+	Password     *string        `mapstructure:"password"`
+	Database     *string        `mapstructure:"database"`
+	SSLMode      *string        `mapstructure:"sslmode"` //仅 postgres
+	MaxIdleConns *int           `mapstructure:"maxIdleConns"`
+	MaxOpenConns *int           `mapstructure:"maxOpenConns"`
+	PingTimeout  *time.Duration `mapstructure:"pingTimeout"`
+	Replicas     []*Replica     `mapstructure:"replicas"`
+	GormOptions  `mapstructure:",squash"`
+}
+
+// GormOptions MySQL、Postgres 和命名数据源共用的连接参数
+type GormOptions struct {
+	Params          *string        `mapstructure:"params"`   //附加的 DSN 参数，格式为 a=1&b=2，同名时覆盖默认参数
+	Timezone        *string        `mapstructure:"timezone"` //mysql 默认 Local，postgres 默认 Asia/Shanghai
+	TLS             *TLS           `mapstructure:"tls"`      //postgres 同时需要设置 sslmode
+	ConnMaxLifetime *time.Duration `mapstructure:"connMaxLifetime"`
+	ConnMaxIdleTime *time.Duration `mapstructure:"connMaxIdleTime"`
+	Logger          *GormLogger    `mapstructure:"logger"`
+}
+
+// GormLogger GORM 的 SQL 日志配置，日志通过 logs 包输出
+type GormLogger struct {
+	Level                *string        `mapstructure:"level"`                //silent、error、warn、info，默认 info
+	SlowThreshold        *time.Duration `mapstructure:"slowThreshold"`        //慢查询阈值，默认 1s
+	ParameterizedQueries *bool          `mapstructure:"parameterizedQueries"` //日志中不输出参数值，默认 true
+	IgnoreNotFound       *bool          `mapstructure:"ignoreNotFound"`       //不记录 record not found 错误，默认 true
+}
+
+// Replica 只读从库，未配置的字段使用主库的配置
+type Replica struct {
+	Host     *string `mapstructure:"host"`
+	Port     *int    `mapstructure:"port"`
+	User     *string `mapstructure:"user"`
+	Password *string `mapstructure:"password"`
+}
+
+// Retry 连接重试策略，重试间隔从 initialInterval 开始每次翻倍，最大为 maxInterval
+type Retry struct {
+	MaxAttempts     *int           `mapstructure:"maxAttempts"`     //最大尝试次数，默认 1 即不重试，0 表示一直重试
+	InitialInterval *time.Duration `mapstructure:"initialInterval"` //默认 1s
+	MaxInterval     *time.Duration `mapstructure:"maxInterval"`     //默认 30s
 }
 
 type Server struct {
@@ -146,6 +292,7 @@ type Server struct {
 	Host         *string        `mapstructure:"host"`
 	ReadTimeout  *time.Duration `mapstructure:"readTimeout"`
 	WriteTimeout *time.Duration `mapstructure:"writeTimeout"`
+	ShowStack    *bool          `mapstructure:"showStack"` //debug 模式下 panic 时是否在响应中返回堆栈
 }
 
 type LogConfig struct {
@@ -208,16 +355,43 @@ func (l *LogConfig) GetMaxBackups() int {
 	return *l.MaxBackups
 }
 
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+
 type Redis struct {
-	Addr         *string `mapstructure:"addr"`
-	Password     *string `mapstructure:"password"`
-	DB           *int    `mapstructure:"db"`
-	PoolSize     *int    `mapstructure:"poolSize"`
-	IdleTimeout  *int    `mapstructure:"idleTimeout"`
-	MaxOpenConns *int    `mapstructure:"maxOpenConns"`
-	MaxIdleConns *int    `mapstructure:"maxIdleConns"`
+	Mode             *string        `mapstructure:"mode"` //standalone、sentinel、cluster，默认 standalone
+	Addr             *string        `mapstructure:"addr"`
+	Addrs            []string       `mapstructure:"addrs"`      //sentinel 模式为哨兵地址，cluster 模式为集群节点地址
+	MasterName       *string        `mapstructure:"masterName"` //sentinel 模式的主节点名称
+	Username         *string        `mapstructure:"username"`   //Redis 6 ACL 用户名
+	Password         *string        `mapstructure:"password"`
+	SentinelUsername *string        `mapstructure:"sentinelUsername"`
+	SentinelPassword *string        `mapstructure:"sentinelPassword"`
+	DB               *int           `mapstructure:"db"` //cluster 模式只支持 0
+	PoolSize         *int           `mapstructure:"poolSize"`
+	IdleTimeout      *int           `mapstructure:"idleTimeout"` //空闲连接的最长保留时间，单位秒
+	MaxOpenConns     *int           `mapstructure:"maxOpenConns"`
+	MaxIdleConns     *int           `mapstructure:"maxIdleConns"`
+	TLS              *TLS           `mapstructure:"tls"`
+	PingTimeout      *time.Duration `mapstructure:"pingTimeout"`
+}
+
+// TLS 客户端 TLS 配置，证书文件均为 PEM 格式
+type TLS struct {
+	Enable             *bool   `mapstructure:"enable"`
+	CaFile             *string `mapstructure:"caFile"`   //自签名证书的 CA，为空时使用系统证书
+	CertFile           *string `mapstructure:"certFile"` //双向认证的客户端证书
+	KeyFile            *string `mapstructure:"keyFile"`
+	ServerName         *string `mapstructure:"serverName"`
+	InsecureSkipVerify *bool   `mapstructure:"insecureSkipVerify"`
 }
 
+
+
 type Mysql struct {
 	Host         *string        `mapstructure:"host"`
 	Port         *int           `mapstructure:"port"`
@@ -227,20 +401,65 @@ type Mysql struct {
 	MaxIdleConns *int           `mapstructure:"maxIdleConns"`
 	PingTimeout  *time.Duration `mapstructure:"pingTimeout"`
 	MaxOpenConns *int           `mapstructure:"maxOpenConns"`
+	Replicas     []*Replica     `mapstructure:"replicas"` //只读从库
+	GormOptions  `mapstructure:",squash"`
 }
 
 type Cache struct {
-	NeedCache []string `mapstructure:"needCache"`
-	Expire *int64 `mapstructure:"expire"` //单位秒
+	NeedCache []string     `mapstructure:"needCache"`
+	Expire    *int64       `mapstructure:"expire"` //单位秒
+	Vary      []string     `mapstructure:"vary"`   //参与缓存 key 计算的请求头，userId 表示当前登录用户
+	Rules     []*CacheRule `mapstructure:"rules"`  //按路径单独配置的缓存规则，优先于 needCache 匹配
+	// StaleWhileRevalidate 缓存过期后仍可返回旧数据的时长，单位秒
+	// 在此期间只有一个请求去刷新缓存，其余请求直接返回旧数据
+	StaleWhileRevalidate *int64 `mapstructure:"staleWhileRevalidate"`
+}
+
+// CacheRule 单条响应缓存规则
+type CacheRule struct {
+	Path    *string  `mapstructure:"path"`    //路径规则，支持 ** 通配
+	Methods []string `mapstructure:"methods"` //缓存的请求方法，默认 GET 和 POST
+	Expire  *int64   `mapstructure:"expire"`  //单位秒，默认使用 cache.expire
+	Vary    []string `mapstructure:"vary"`    //默认使用 cache.vary
+	// StaleWhileRevalidate 单位秒，默认使用 cache.staleWhileRevalidate
+	StaleWhileRevalidate *int64 `mapstructure:"staleWhileRevalidate"`
+	// Tags 缓存标签，支持 {name} 占位符，依次从路径参数、查询参数和 userId 中取值，如 article:{id}
+	Tags []string `mapstructure:"tags"`
 }
 
 func (c *Cache) GetExpire() int64 {
 	if c == nil || c.Expire == nil {
-		return 3600 // 默认1小时
+		return 300 // 默认5分钟
 	}
 	return *c.Expire
 }
 
+func (c *Cache) GetStaleWhileRevalidate() int64 {
+	if c == nil || c.StaleWhileRevalidate == nil {
+		return 0
+	}
+	return *c.StaleWhileRevalidate
+}
+
+func (c *Cache) GetVary() []string {
+	if c == nil || c.Vary == nil {
+		return []string{}
+	}
+	return c.Vary
+}
+
+// IsEnable 配置了 needCache 或 rules 时启用缓存中间件
+func (c *Cache) IsEnable() bool {
+	return len(c.GetNeedCache()) > 0 || (c != nil && len(c.Rules) > 0)
+}
+
+func (r *CacheRule) GetPath() string {
+	if r == nil || r.Path == nil {
+		return ""
+	}
+	return *r.Path
+}
+
 func (c *Cache) GetNeedCache() []string {
 	if c == nil || c.NeedCache == nil {
 		return []string{}
@@ -248,6 +467,87 @@ func (c *Cache) GetNeedCache() []string {
 	return c.NeedCache
 }
 
+// RateLimit 限流配置
+type RateLimit struct {
+	Enable *bool            `mapstructure:"enable"`
+	Prefix *string          `mapstructure:"prefix"` //redis key 前缀
+	Rules  []*RateLimitRule `mapstructure:"rules"`
+}
+
+// RateLimitRule 限流规则，请求路径匹配多条规则时需要全部通过
+type RateLimitRule struct {
+	Path      *string        `mapstructure:"path"`      //路径规则，支持 ** 通配
+	Method    *string        `mapstructure:"method"`    //请求方法，为空时匹配所有方法
+	KeyBy     *string        `mapstructure:"keyBy"`     //限流维度 ip | user | header，默认 ip
+	Header    *string        `mapstructure:"header"`    //keyBy 为 header 时使用的请求头
+	Algorithm *string        `mapstructure:"algorithm"` //限流算法 token_bucket | sliding_window，默认 sliding_window
+	Limit     *int           `mapstructure:"limit"`     //窗口内允许的请求数，令牌桶时为桶容量
+	Window    *time.Duration `mapstructure:"window"`    //窗口大小，令牌桶时每个窗口补充 limit 个令牌
+}
+
+func (r *RateLimit) GetEnable() bool {
+	if r == nil || r.Enable == nil {
+		return false
+	}
+	return *r.Enable
+}
+
+func (r *RateLimit) GetPrefix() string {
+	if r == nil || r.Prefix == nil {
+		return "RATELIMIT"
+	}
+	return *r.Prefix
+}
+
+func (r *RateLimitRule) GetPath() string {
+	if r == nil || r.Path == nil {
+		return "/**"
+	}
+	return *r.Path
+}
+
+func (r *RateLimitRule) GetMethod() string {
+	if r == nil || r.Method == nil {
+		return ""
+	}
+	return *r.Method
+}
+
+func (r *RateLimitRule) GetKeyBy() string {
+	if r == nil || r.KeyBy == nil {
+		return "ip"
+	}
+	return *r.KeyBy
+}
+
+func (r *RateLimitRule) GetHeader() string {
+	if r == nil || r.Header == nil {
+		return ""
+	}
+	return *r.Header
+}
+
+func (r *RateLimitRule) GetAlgorithm() string {
+	if r == nil || r.Algorithm == nil {
+		return "sliding_window"
+	}
+	return *r.Algorithm
+}
+
+func (r *RateLimitRule) GetLimit() int {
+	if r == nil || r.Limit == nil {
+		return 60
+	}
+	return *r.Limit
+}
+
+func (r *RateLimitRule) GetWindow() time.Duration {
+	if r == nil || r.Window == nil {
+		return time.Minute
+	}
+	return *r.Window
+}
+
 type Upload struct {
 	Prefix *string `mapstructure:"prefix"`
 }
@@ -271,6 +571,102 @@ type Auth struct {
 	IsAuth *bool `mapstructure:"isAuth"`
 	Ignores    []string `mapstructure:"ignores"`
 	NeedLogins []string `mapstructure:"needLogins"`
+	ApiKey     *ApiKey  `mapstructure:"apiKey"`
+}
+
+// ApiKey API key 认证配置，用于定时任务、合作方系统等无法走登录流程的调用方
+type ApiKey struct {
+	Enable *bool         `mapstructure:"enable"`
+	Header *string       `mapstructure:"header"` //携带 API key 的请求头，默认 X-API-Key
+	Paths  []string      `mapstructure:"paths"`  //允许使用 API key 的路径，为空时所有路径都允许
+	Only   []string      `mapstructure:"only"`   //只允许使用 API key 的路径，这些路径不接受 JWT
+	Keys   []*ApiKeyItem `mapstructure:"keys"`   //静态配置的 API key
+}
+
+// ApiKeyItem 静态配置的 API key，只保存 key 的 sha256 摘要
+type ApiKeyItem struct {
+	Name      *string  `mapstructure:"name"`
+	Hash      *string  `mapstructure:"hash"`
+	Principal *string  `mapstructure:"principal"` //认证通过后作为 userId 使用
+	Scopes    []string `mapstructure:"scopes"`    //作为权限交给授权中间件校验
+}
+
+func (a *ApiKey) GetEnable() bool {
+	if a == nil || a.Enable == nil {
+		return false
+	}
+	return *a.Enable
+}
+
+func (a *ApiKey) GetHeader() string {
+	if a == nil || a.Header == nil {
+		return "X-API-Key"
+	}
+	return *a.Header
+}
+
+func (i *ApiKeyItem) GetName() string {
+	if i == nil || i.Name == nil {
+		return ""
+	}
+	return *i.Name
+}
+
+func (i *ApiKeyItem) GetHash() string {
+	if i == nil || i.Hash == nil {
+		return ""
+	}
+	return *i.Hash
+}
+
+func (i *ApiKeyItem) GetPrincipal() string {
+	if i == nil || i.Principal == nil {
+		return i.GetName()
+	}
+	return *i.Principal
+}
+
+// Authz 授权配置，在 Auth 认证通过后按规则校验角色和权限
+type Authz struct {
+	Enable     *bool        `mapstructure:"enable"`
+	PolicyFile *string      `mapstructure:"policyFile"` //Casbin 风格的策略文件，p 行定义角色权限，g 行定义用户角色
+	Rules      []*AuthzRule `mapstructure:"rules"`
+}
+
+// AuthzRule 授权规则，请求匹配多条规则时需要全部满足
+type AuthzRule struct {
+	Method      *string  `mapstructure:"method"`      //请求方法，为空时匹配所有方法
+	Path        *string  `mapstructure:"path"`        //路径规则，支持 ** 通配
+	Roles       []string `mapstructure:"roles"`       //拥有其中任一角色即可
+	Permissions []string `mapstructure:"permissions"` //需要拥有全部权限
+}
+
+func (a *Authz) GetEnable() bool {
+	if a == nil || a.Enable == nil {
+		return false
+	}
+	return *a.Enable
+}
+
+func (a *Authz) GetPolicyFile() string {
+	if a == nil || a.PolicyFile == nil {
+		return ""
+	}
+	return *a.PolicyFile
+}
+
+func (r *AuthzRule) GetMethod() string {
+	if r == nil || r.Method == nil {
+		return ""
+	}
+	return *r.Method
+}
+
+func (r *AuthzRule) GetPath() string {
+	if r == nil || r.Path == nil {
+		return "/**"
+	}
+	return *r.Path
 }
 
 type Wx struct {
@@ -280,6 +676,8 @@ type Wx struct {
 	AesKey *string `mapstructure:"aesKey"`
 }
 
+
+
 type Postgres struct {
 	Host         *string        `mapstructure:"host"`
 	Port         *int           `mapstructure:"port"`
@@ -290,6 +688,8 @@ type Postgres struct {
 	MaxIdleConns *int           `mapstructure:"maxIdleConns"`
 	PingTimeout  *time.Duration `mapstructure:"pingTimeout"`
 	MaxOpenConns *int           `mapstructure:"maxOpenConns"`
+	Replicas     []*Replica     `mapstructure:"replicas"` //只读从库
+	GormOptions  `mapstructure:",squash"`
 }
 
 func (s *Server) GetHost() string {
@@ -327,6 +727,13 @@ func (s *Server) GetWriteTimeout() time.Duration {
 	return *s.WriteTimeout
 }
 
+func (s *Server) GetShowStack() bool {
+	if s == nil || s.ShowStack == nil {
+		return false
+	}
+	return *s.ShowStack
+}
+
 func (s *Server) GetCros() []string {
 	if s == nil || s.Cros == nil {
 		return []string{}
@@ -461,6 +868,147 @@ func (r *Redis) GetMaxOpenConns() int {
 	return *r.MaxOpenConns
 }
 
+func (r *Redis) GetMode() string {
+	if r == nil || r.Mode == nil {
+		return RedisModeStandalone
+	}
+	return *r.Mode
+}
+
+// GetAddrs sentinel、cluster 模式的节点地址，未配置时使用 addr
+func (r *Redis) GetAddrs() []string {
+	if r == nil || len(r.Addrs) == 0 {
+		return []string{r.GetAddr()}
+	}
+	return r.Addrs
+}
+
+func (r *Redis) GetMasterName() string {
+	if r == nil || r.MasterName == nil {
+		return "mymaster"
+	}
+	return *r.MasterName
+}
+
+func (r *Redis) GetUsername() string {
+	if r == nil || r.Username == nil {
+		return ""
+	}
+	return *r.Username
+}
+
+func (r *Redis) GetSentinelUsername() string {
+	if r == nil || r.SentinelUsername == nil {
+		return ""
+	}
+	return *r.SentinelUsername
+}
+
+func (r *Redis) GetSentinelPassword() string {
+	if r == nil || r.SentinelPassword == nil {
+		return ""
+	}
+	return *r.SentinelPassword
+}
+
+func (r *Redis) GetIdleTimeout() int {
+	if r == nil || r.IdleTimeout == nil {
+		return 0
+	}
+	return *r.IdleTimeout
+}
+
+func (r *Redis) GetPingTimeout() time.Duration {
+	if r == nil || r.PingTimeout == nil {
+		return 5 * time.Second
+	}
+	return *r.PingTimeout
+}
+
+func (d *DB) GetRetry() *Retry {
+	if d == nil {
+		return nil
+	}
+	return d.Retry
+}
+
+func (d *DB) GetDegraded() bool {
+	if d == nil || d.Degraded == nil {
+		return false
+	}
+	return *d.Degraded
+}
+
+func (r *Retry) GetMaxAttempts() int {
+	if r == nil || r.MaxAttempts == nil {
+		return 1
+	}
+	return *r.MaxAttempts
+}
+
+func (r *Retry) GetInitialInterval() time.Duration {
+	if r == nil || r.InitialInterval == nil {
+		return time.Second
+	}
+	return *r.InitialInterval
+}
+
+func (r *Retry) GetMaxInterval() time.Duration {
+	if r == nil || r.MaxInterval == nil {
+		return 30 * time.Second
+	}
+	return *r.MaxInterval
+}
+
+func (r *Redis) GetTLS() *TLS {
+	if r == nil {
+		return nil
+	}
+	return r.TLS
+}
+
+func (t *TLS) GetEnable() bool {
+	if t == nil || t.Enable == nil {
+		return false
+	}
+	return *t.Enable
+}
+
+func (t *TLS) GetCaFile() string {
+	if t == nil || t.CaFile == nil {
+		return ""
+	}
+	return *t.CaFile
+}
+
+func (t *TLS) GetCertFile() string {
+	if t == nil || t.CertFile == nil {
+		return ""
+	}
+	return *t.CertFile
+}
+
+func (t *TLS) GetKeyFile() string {
+	if t == nil || t.KeyFile == nil {
+		return ""
+	}
+	return *t.KeyFile
+}
+
+func (t *TLS) GetServerName() string {
+	if t == nil || t.ServerName == nil {
+		return ""
+	}
+	return *t.ServerName
+}
+
+func (t *TLS) GetInsecureSkipVerify() bool {
+	if t == nil || t.InsecureSkipVerify == nil {
+		return false
+	}
+	return *t.InsecureSkipVerify
+}
+
 func (c *Config) GetJwt() *Jwt {
 	if c == nil {
 		return nil
@@ -482,6 +1030,114 @@ func (a *Auth) GetIgnores() []string {
 	return a.Ignores
 }
 
+// Tenant 多租户配置，租户标识按 sources 的顺序解析，先命中的来源生效
+type Tenant struct {
+	Enable   *bool    `mapstructure:"enable"`
+	Sources  []string `mapstructure:"sources"`  //解析来源 jwt | header | subdomain，默认 jwt、header
+	Header   *string  `mapstructure:"header"`   //header 来源使用的请求头，默认 X-Tenant-ID
+	Domain   *string  `mapstructure:"domain"`   //subdomain 来源的主域名，如 example.com，acme.example.com 解析为 acme
+	Required *bool    `mapstructure:"required"` //解析不到租户时是否拒绝请求，默认 true
+	Ignores  []string `mapstructure:"ignores"`  //不需要租户的路径，支持 ** 通配
+	Column   *string  `mapstructure:"column"`   //数据表中的租户列，默认 tenant_id
+	// TrustUnverified token 中没有租户或匿名请求时，是否信任 header 和 subdomain 解析出的租户，默认 false
+	// 只应在网关已经校验过租户请求头等可信环境中开启
+	TrustUnverified *bool `mapstructure:"trustUnverified"`
+}
+
+func (t *Tenant) GetEnable() bool {
+	if t == nil || t.Enable == nil {
+		return false
+	}
+	return *t.Enable
+}
+
+func (t *Tenant) GetSources() []string {
+	if t == nil || len(t.Sources) == 0 {
+		return []string{TenantSourceJwt, TenantSourceHeader}
+	}
+	return t.Sources
+}
+
+func (t *Tenant) GetHeader() string {
+	if t == nil || t.Header == nil {
+		return "X-Tenant-ID"
+	}
+	return *t.Header
+}
+
+func (t *Tenant) GetDomain() string {
+	if t == nil || t.Domain == nil {
+		return ""
+	}
+	return *t.Domain
+}
+
+func (t *Tenant) GetRequired() bool {
+	if t == nil || t.Required == nil {
+		return true
+	}
+	return *t.Required
+}
+
+func (t *Tenant) GetIgnores() []string {
+	if t == nil || t.Ignores == nil {
+		return []string{}
+	}
+	return t.Ignores
+}
+
+func (t *Tenant) GetTrustUnverified() bool {
+	if t == nil || t.TrustUnverified == nil {
+		return false
+	}
+	return *t.TrustUnverified
+}
+
+func (t *Tenant) GetColumn() string {
+	if t == nil || t.Column == nil {
+		return "tenant_id"
+	}
+	return *t.Column
+}
+
+const (
+	TenantSourceJwt       = "jwt"
+	TenantSourceHeader    = "header"
+	TenantSourceSubdomain = "subdomain"
+)
+
+// Crypto 加密配置
+type Crypto struct {
+	AesKeys []*AesKey `mapstructure:"aesKeys"` //第一个密钥用于加密，其余只用于解密，便于轮换
+}
+
+// AesKey AES 密钥，key 为 16、24、32 字节的字符串或其 base64 编码
+type AesKey struct {
+	Id  *string `mapstructure:"id"`
+	Key *string `mapstructure:"key"`
+}
+
+func (c *Crypto) GetAesKeys() []*AesKey {
+	if c == nil || c.AesKeys == nil {
+		return []*AesKey{}
+	}
+	return c.AesKeys
+}
+
+func (k *AesKey) GetId() string {
+	if k == nil || k.Id == nil {
+		return ""
+	}
+	return *k.Id
+}
+
+func (k *AesKey) GetKey() string {
+	if k == nil || k.Key == nil {
+		return ""
+	}
+	return *k.Key
+}
+
 func InitConfig() {
 	workDir, _ := os.Getwd()
 	viper.SetConfigName("config")
@@ -628,3 +1284,188 @@ func (m *Mysql) GetMaxOpenConns() int {
 	}
 	return *m.MaxOpenConns
 }
+
+const (
+	DriverMysql    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+func (d *Datasource) GetName() string {
+	if d == nil || d.Name == nil {
+		return ""
+	}
+	return *d.Name
+}
+
+func (d *Datasource) GetDriver() string {
+	if d == nil || d.Driver == nil {
+		return DriverMysql
+	}
+	return *d.Driver
+}
+
+func (d *Datasource) GetHost() string {
+	if d == nil || d.Host == nil {
+		return "127.0.0.1"
+	}
+	return *d.Host
+}
+
+// GetPort 未配置时按 driver 使用默认端口
+func (d *Datasource) GetPort() int {
+	if d == nil || d.Port == nil {
+		if d.GetDriver() == DriverPostgres {
+			return 5432
+		}
+		return 3306
+	}
+	return *d.Port
+}
+
+func (d *Datasource) GetUser() string {
+	if d == nil || d.User == nil {
+		return ""
+	}
+	return *d.User
+}
+
+func (d *Datasource) GetPassword() string {
+	if d == nil || d.Password == nil {
+		return ""
+	}
+	return *d.Password
+}
+
+func (d *Datasource) GetDatabase() string {
+	if d == nil || d.Database == nil {
+		return ""
+	}
+	return *d.Database
+}
+
+func (d *Datasource) GetSSLMode() string {
+	if d == nil || d.SSLMode == nil {
+		return "disable"
+	}
+	return *d.SSLMode
+}
+
+func (d *Datasource) GetMaxIdleConns() int {
+	if d == nil || d.MaxIdleConns == nil {
+		return 10
+	}
+	return *d.MaxIdleConns
+}
+
+func (d *Datasource) GetMaxOpenConns() int {
+	if d == nil || d.MaxOpenConns == nil {
+		return 100
+	}
+	return *d.MaxOpenConns
+}
+
+func (d *Datasource) GetPingTimeout() time.Duration {
+	if d == nil || d.PingTimeout == nil {
+		return 5 * time.Second
+	}
+	return *d.PingTimeout
+}
+
+// GetHost 未配置时返回主库的 host，其余 getter 相同
+func (r *Replica) GetHost(primary string) string {
+	if r == nil || r.Host == nil {
+		return primary
+	}
+	return *r.Host
+}
+
+func (r *Replica) GetPort(primary int) int {
+	if r == nil || r.Port == nil {
+		return primary
+	}
+	return *r.Port
+}
+
+func (r *Replica) GetUser(primary string) string {
+	if r == nil || r.User == nil {
+		return primary
+	}
+	return *r.User
+}
+
+func (r *Replica) GetPassword(primary string) string {
+	if r == nil || r.Password == nil {
+		return primary
+	}
+	return *r.Password
+}
+
+func (g *GormOptions) GetParams() string {
+	if g == nil || g.Params == nil {
+		return ""
+	}
+	return *g.Params
+}
+
+func (g *GormOptions) GetTimezone(def string) string {
+	if g == nil || g.Timezone == nil {
+		return def
+	}
+	return *g.Timezone
+}
+
+func (g *GormOptions) GetTLS() *TLS {
+	if g == nil {
+		return nil
+	}
+	return g.TLS
+}
+
+func (g *GormOptions) GetConnMaxLifetime() time.Duration {
+	if g == nil || g.ConnMaxLifetime == nil {
+		return 0
+	}
+	return *g.ConnMaxLifetime
+}
+
+func (g *GormOptions) GetConnMaxIdleTime() time.Duration {
+	if g == nil || g.ConnMaxIdleTime == nil {
+		return 0
+	}
+	return *g.ConnMaxIdleTime
+}
+
+func (g *GormOptions) GetLogger() *GormLogger {
+	if g == nil {
+		return nil
+	}
+	return g.Logger
+}
+
+func (g *GormLogger) GetLevel() string {
+	if g == nil || g.Level == nil {
+		return "info"
+	}
+	return *g.Level
+}
+
+func (g *GormLogger) GetSlowThreshold() time.Duration {
+	if g == nil || g.SlowThreshold == nil {
+		return time.Second
+	}
+	return *g.SlowThreshold
+}
+
+func (g *GormLogger) GetParameterizedQueries() bool {
+	if g == nil || g.ParameterizedQueries == nil {
+		return true
+	}
+	return *g.ParameterizedQueries
+}
+
+func (g *GormLogger) GetIgnoreNotFound() bool {
+	if g == nil || g.IgnoreNotFound == nil {
+		return true
+	}
+	return *g.IgnoreNotFound
+}
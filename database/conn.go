@@ -0,0 +1,89 @@
+package database
+
+import (
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/db"
+)
+
+// connConf config.Mysql、config.Postgres 和 config.Datasource 共有的连接配置
+type connConf interface {
+	GetHost() string
+	GetPort() int
+	GetUser() string
+	GetPassword() string
+	GetDatabase() string
+	GetMaxIdleConns() int
+	GetMaxOpenConns() int
+	GetPingTimeout() time.Duration
+	GetParams() string
+	GetTimezone(def string) string
+	GetTLS() *config.TLS
+	GetConnMaxLifetime() time.Duration
+	GetConnMaxIdleTime() time.Duration
+	GetLogger() *config.GormLogger
+}
+
+// pgConnConf Postgres 额外需要 sslmode
+type pgConnConf interface {
+	connConf
+	GetSSLMode() string
+}
+
+// newMySQL 按配置创建 MySQL 连接，未连接，需要调用 Init
+func newMySQL(conf connConf, replicas []*config.Replica) *db.MySQL {
+	return &db.MySQL{
+		Database:        conf.GetDatabase(),
+		Host:            conf.GetHost(),
+		MaxIdleConns:    conf.GetMaxIdleConns(),
+		MaxOpenConns:    conf.GetMaxOpenConns(),
+		Password:        conf.GetPassword(),
+		Port:            conf.GetPort(),
+		Username:        conf.GetUser(),
+		PingTimeout:     conf.GetPingTimeout(),
+		ConnMaxLifetime: conf.GetConnMaxLifetime(),
+		ConnMaxIdleTime: conf.GetConnMaxIdleTime(),
+		Params:          conf.GetParams(),
+		Timezone:        conf.GetTimezone(""),
+		TLS:             conf.GetTLS(),
+		Logger:          conf.GetLogger(),
+		Replicas:        toReplicas(conf, replicas),
+	}
+}
+
+// newPostgres 按配置创建 Postgres 连接，未连接，需要调用 Init
+func newPostgres(conf pgConnConf, replicas []*config.Replica) *db.Postgres {
+	return &db.Postgres{
+		Database:        conf.GetDatabase(),
+		Host:            conf.GetHost(),
+		MaxIdleConns:    conf.GetMaxIdleConns(),
+		MaxOpenConns:    conf.GetMaxOpenConns(),
+		Password:        conf.GetPassword(),
+		Port:            conf.GetPort(),
+		Username:        conf.GetUser(),
+		SSLMode:         conf.GetSSLMode(),
+		PingTimeout:     conf.GetPingTimeout(),
+		ConnMaxLifetime: conf.GetConnMaxLifetime(),
+		ConnMaxIdleTime: conf.GetConnMaxIdleTime(),
+		Params:          conf.GetParams(),
+		Timezone:        conf.GetTimezone(""),
+		TLS:             conf.GetTLS(),
+		Logger:          conf.GetLogger(),
+		Replicas:        toReplicas(conf, replicas),
+	}
+}
+
+// toReplicas 从库未配置的连接信息继承主库
+func toReplicas(primary connConf, replicas []*config.Replica) []db.Replica {
+	var result []db.Replica
+	for _, r := range replicas {
+		result = append(result, db.Replica{
+			Host:     r.GetHost(primary.GetHost()),
+			Port:     r.GetPort(primary.GetPort()),
+			Username: r.GetUser(primary.GetUser()),
+			Password: r.GetPassword(primary.GetPassword()),
+		})
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestBuildersShareDatasourceOptions(t *testing.T) {
+	options := config.GormOptions{Params: gptr.Of("readTimeout=3s"), Timezone: gptr.Of("UTC"), ConnMaxLifetime: gptr.Of(time.Hour)}
+	replicas := []*config.Replica{{Host: gptr.Of("replica")}}
+	mysql := newMySQL(&config.Mysql{Host: gptr.Of("primary"), User: gptr.Of("u"), Replicas: replicas, GormOptions: options}, replicas)
+	ds := newMySQL(&config.Datasource{Host: gptr.Of("primary"), User: gptr.Of("u"), GormOptions: options}, replicas)
+	if !reflect.DeepEqual(mysql, ds) {
+		t.Fatalf("mysql and datasource should build the same connection:\n%+v\n%+v", mysql, ds)
+	}
+	// 从库未配置的字段继承主库
+	if r := mysql.Replicas[0]; r.Host != "replica" || r.Username != "u" || r.Port != mysql.Port {
+		t.Fatalf("unexpected replica %+v", r)
+	}
+
+	pg := newPostgres(&config.Postgres{SSLMode: gptr.Of("require"), GormOptions: options}, nil)
+	pgDs := newPostgres(&config.Datasource{Driver: gptr.Of(config.DriverPostgres), SSLMode: gptr.Of("require"), GormOptions: options}, nil)
+	if pg.SSLMode != "require" || pg.Timezone != pgDs.Timezone || pg.Params != pgDs.Params {
+		t.Fatalf("postgres builders differ: %+v %+v", pg, pgDs)
+	}
+}
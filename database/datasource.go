@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"gorm.io/gorm"
+)
+
+// StoreDatasourcePrefix 命名数据源在 Manager 中的存储名称前缀
+const StoreDatasourcePrefix = "datasource:"
+
+var (
+	datasourceMu sync.RWMutex
+	datasources  = make(map[string]*gorm.DB)
+)
+
+// ConnectDatasource 按重试策略连接命名数据源，成功后可以通过 GetDatasource 按名称获取
+func ConnectDatasource(ctx context.Context, conf *config.Datasource, policy *config.Retry) (Store, error) {
+	var (
+		s      Store
+		gormDB func() *gorm.DB
+		err    error
+	)
+	if conf.GetName() == "" {
+		return nil, errors.New("database: datasource name is required")
+	}
+	switch conf.GetDriver() {
+	case config.DriverMysql:
+		m := newMySQL(conf, conf.Replicas)
+		err = Retry(ctx, policy, StoreDatasourcePrefix+conf.GetName(), m.Init)
+		s, gormDB = m, func() *gorm.DB { return m.GormDB }
+	case config.DriverPostgres:
+		p := newPostgres(conf, conf.Replicas)
+		err = Retry(ctx, policy, StoreDatasourcePrefix+conf.GetName(), p.Init)
+		s, gormDB = p, func() *gorm.DB { return p.GormDB }
+	default:
+		return nil, fmt.Errorf("database: unsupported driver %q for datasource %q", conf.GetDriver(), conf.GetName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	datasourceMu.Lock()
+	datasources[conf.GetName()] = gormDB()
+	datasourceMu.Unlock()
+	return s, nil
+}
+
+// GetDatasource 按名称获取已连接的数据源，不存在时返回 nil
+// 配置了从库时读请求自动路由到从库，需要强制读主库时使用 db.ForcePrimary(ctx) 或 Clauses(dbresolver.Write)
+func GetDatasource(name string) *gorm.DB {
+	datasourceMu.RLock()
+	defer datasourceMu.RUnlock()
+	return datasources[name]
+}
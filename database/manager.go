@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/db"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+	"gorm.io/gorm"
+)
+
+const (
+	StoreMysql    = "mysql"
+	StorePostgres = "postgres"
+	StoreRedis    = "redis"
+)
+
+// ErrNotConnected 存储尚未连接成功，降级模式下后台仍在重连
+var ErrNotConnected = errors.New("database: not connected")
+
+// Store 由 Manager 管理生命周期的存储
+type Store interface {
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Manager 统一管理配置中所有存储的连接、健康检查和关闭
+// 连接成功后同时设置 GetMysqlDB、GetPostgresDB 和 GetRedis 使用的全局变量，后台重连成功后也会更新
+//
+//	manager := database.NewManager(conf.DB)
+//	if err := manager.Init(ctx); err != nil {
+//		return err
+//	}
+//	defer manager.Close()
+type Manager struct {
+	conf     *config.DB
+	mu       sync.RWMutex
+	stores   map[string]Store
+	failures map[string]error
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewManager(conf *config.DB) *Manager {
+	return &Manager{
+		conf:     conf,
+		stores:   make(map[string]Store),
+		failures: make(map[string]error),
+	}
+}
+
+// Init 按重试策略连接所有配置的存储
+// 非降级模式下任一存储连接失败都返回错误；降级模式下只记录失败，并在后台一直重连直到 Close
+func (m *Manager) Init(ctx context.Context) error {
+	bg, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.cancel = cancel
+	policy := m.conf.GetRetry()
+	var errs []error
+	for name, connect := range m.connectors() {
+		err := connect(ctx, policy)
+		if err == nil {
+			continue
+		}
+		if !m.conf.GetDegraded() {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		logs.CtxError(ctx, "store unavailable, starting in degraded mode", "store", name, "error", err)
+		m.setFailure(name, err)
+		m.wg.Add(1)
+		go m.reconnect(bg, name, connect)
+	}
+	// 兼容直接读取 RedisCli 的代码，只在 Init 中同步赋值，后台重连只更新 GetRedis
+	if r := m.Redis(); r != nil {
+		RedisCli = r
+	}
+	return errors.Join(errs...)
+}
+
+// Ping 检查每个已配置存储的连接，返回存储名称到错误的映射，正常的存储对应 nil
+func (m *Manager) Ping(ctx context.Context) map[string]error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]error, len(m.stores)+len(m.failures))
+	for name, s := range m.stores {
+		result[name] = s.Ping(ctx)
+	}
+	for name, err := range m.failures {
+		result[name] = fmt.Errorf("%w: %v", ErrNotConnected, err)
+	}
+	return result
+}
+
+// Health 所有存储都正常时返回 nil，适合直接用于健康检查接口
+func (m *Manager) Health(ctx context.Context) error {
+	var errs []error
+	for name, err := range m.Ping(ctx) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 停止后台重连并关闭所有连接
+func (m *Manager) Close() error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for name, s := range m.stores {
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	m.stores = make(map[string]Store)
+	return errors.Join(errs...)
+}
+
+func (m *Manager) Mysql() *db.MySQL {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, _ := m.stores[StoreMysql].(*db.MySQL)
+	return s
+}
+
+func (m *Manager) Postgres() *db.Postgres {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, _ := m.stores[StorePostgres].(*db.Postgres)
+	return s
+}
+
+func (m *Manager) Redis() *db.Redis {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, _ := m.stores[StoreRedis].(*db.Redis)
+	return s
+}
+
+// Datasource 按名称获取已连接的命名数据源，不存在时返回 nil
+func (m *Manager) Datasource(name string) *gorm.DB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.stores[StoreDatasourcePrefix+name]; !ok {
+		return nil
+	}
+	return GetDatasource(name)
+}
+
+type connector func(ctx context.Context, policy *config.Retry) error
+
+func (m *Manager) connectors() map[string]connector {
+	connectors := make(map[string]connector)
+	if m.conf.Mysql != nil {
+		connectors[StoreMysql] = func(ctx context.Context, policy *config.Retry) error {
+			s, err := ConnectMysql(ctx, m.conf.Mysql, policy)
+			if err != nil {
+				return err
+			}
+			_db.Store(s)
+			m.setStore(StoreMysql, s)
+			return nil
+		}
+	}
+	if m.conf.Postgres != nil {
+		connectors[StorePostgres] = func(ctx context.Context, policy *config.Retry) error {
+			s, err := ConnectPostgres(ctx, m.conf.Postgres, policy)
+			if err != nil {
+				return err
+			}
+			_pg.Store(s)
+			m.setStore(StorePostgres, s)
+			return nil
+		}
+	}
+	if m.conf.Redis != nil {
+		connectors[StoreRedis] = func(ctx context.Context, policy *config.Retry) error {
+			s, err := ConnectRedis(ctx, m.conf.Redis, policy)
+			if err != nil {
+				return err
+			}
+			_redis.Store(s)
+			m.setStore(StoreRedis, s)
+			return nil
+		}
+	}
+	for _, ds := range m.conf.Datasources {
+		name := StoreDatasourcePrefix + ds.GetName()
+		connectors[name] = func(ctx context.Context, policy *config.Retry) error {
+			s, err := ConnectDatasource(ctx, ds, policy)
+			if err != nil {
+				return err
+			}
+			m.setStore(name, s)
+			return nil
+		}
+	}
+	return connectors
+}
+
+// reconnect 降级模式下在后台不限次数地重连
+func (m *Manager) reconnect(ctx context.Context, name string, connect connector) {
+	defer m.wg.Done()
+	policy := m.conf.GetRetry()
+	forever := &config.Retry{
+		MaxAttempts:     gptr.Of(0),
+		InitialInterval: gptr.Of(policy.GetInitialInterval()),
+		MaxInterval:     gptr.Of(policy.GetMaxInterval()),
+	}
+	if err := connect(ctx, forever); err != nil {
+		return
+	}
+	logs.CtxInfo(ctx, "store reconnected", "store", name)
+}
+
+func (m *Manager) setStore(name string, s Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stores[name] = s
+	delete(m.failures, name)
+}
+
+func (m *Manager) setFailure(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[name] = err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func unreachableRedis(degraded bool) *config.DB {
+	return &config.DB{
+		Redis: &config.Redis{
+			Addr:        gptr.Of("127.0.0.1:1"),
+			PingTimeout: gptr.Of(100 * time.Millisecond),
+		},
+		Retry: &config.Retry{
+			MaxAttempts:     gptr.Of(2),
+			InitialInterval: gptr.Of(10 * time.Millisecond),
+		},
+		Degraded: gptr.Of(degraded),
+	}
+}
+
+func TestManagerInitReturnsError(t *testing.T) {
+	m := NewManager(unreachableRedis(false))
+	if err := m.Init(context.Background()); err == nil {
+		t.Fatal("want connect error")
+	}
+	_ = m.Close()
+}
+
+func TestManagerDegraded(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(unreachableRedis(true))
+	if err := m.Init(ctx); err != nil {
+		t.Fatalf("degraded init should not fail: %v", err)
+	}
+	if err := m.Health(ctx); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("want ErrNotConnected, got %v", err)
+	}
+	if m.Redis() != nil {
+		t.Fatal("redis should not be connected")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryStopsAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	policy := &config.Retry{MaxAttempts: gptr.Of(3), InitialInterval: gptr.Of(time.Millisecond)}
+	err := Retry(context.Background(), policy, "test", func() error {
+		attempts++
+		return errors.New("down")
+	})
+	if err == nil || attempts != 3 {
+		t.Fatalf("attempts %d, err %v", attempts, err)
+	}
+}
+
+func TestGlobalsSafeDuringReconnect(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	conf := unreachableRedis(true)
+	conf.Redis.Addr = gptr.Of(addr)
+	old := GetRedis()
+	defer SetRedis(old)
+	SetRedis(nil)
+
+	m := NewManager(conf)
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	// 后台重连写入全局连接的同时并发读取，配合 go test -race 检查数据竞争
+	deadline := time.Now().Add(5 * time.Second)
+	for GetRedis() == nil {
+		_ = GetMysqlDB()
+		_ = GetPostgresDB()
+		if time.Now().After(deadline) {
+			t.Fatal("redis should reconnect in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.Redis() != GetRedis() {
+		t.Fatal("manager and global should share the reconnected client")
+	}
+}
@@ -1,36 +1,39 @@
 package database
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/zhangc-zwl/thunder/config"
 	"github.com/zhangc-zwl/thunder/db"
 )
 
 var (
-	_db *db.MySQL
+	// _db 降级模式下由后台重连的 goroutine 写入，只能通过 GetMysqlDB 读取
+	_db atomic.Pointer[db.MySQL]
 )
 
+// InitDB 连接 MySQL，失败时 panic，需要处理错误时使用 ConnectMysql 或 Manager
 func InitDB(mysqlConf *config.Mysql) {
 	if mysqlConf == nil {
 		return
 	}
-
-	m := db.MySQL{
-		Database:     mysqlConf.GetDatabase(),
-		Host:         mysqlConf.GetHost(),
-		MaxIdleConns: mysqlConf.GetMaxIdleConns(),
-		MaxOpenConns: mysqlConf.GetMaxOpenConns(),
-		Password:     mysqlConf.GetPassword(),
-		Port:         mysqlConf.GetPort(),
-		Username:     mysqlConf.GetUser(),
-		PingTimeout:  mysqlConf.GetPingTimeout(),
-	}
-	err := m.Init()
+	m, err := ConnectMysql(context.Background(), mysqlConf, nil)
 	if err != nil {
 		panic(err)
 	}
-	_db = &m
+	_db.Store(m)
+}
+
+// ConnectMysql 按重试策略连接 MySQL，policy 为 nil 时只尝试一次
+func ConnectMysql(ctx context.Context, mysqlConf *config.Mysql, policy *config.Retry) (*db.MySQL, error) {
+	m := newMySQL(mysqlConf, mysqlConf.Replicas)
+	if err := Retry(ctx, policy, StoreMysql, m.Init); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 func GetMysqlDB() *db.MySQL {
-	return _db
-}
\ No newline at end of file
+	return _db.Load()
+}
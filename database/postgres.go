@@ -1,37 +1,39 @@
 package database
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/zhangc-zwl/thunder/config"
 	"github.com/zhangc-zwl/thunder/db"
 )
 
 var (
-	_pg *db.Postgres
+	// _pg 降级模式下由后台重连的 goroutine 写入，只能通过 GetPostgresDB 读取
+	_pg atomic.Pointer[db.Postgres]
 )
 
+// InitPostgres 连接 Postgres，失败时 panic，需要处理错误时使用 ConnectPostgres 或 Manager
 func InitPostgres(pgConf *config.Postgres) {
 	if pgConf == nil {
 		return
 	}
-
-	p := db.Postgres{
-		Database:     pgConf.GetDatabase(),
-		Host:         pgConf.GetHost(),
-		MaxIdleConns: pgConf.GetMaxIdleConns(),
-		MaxOpenConns: pgConf.GetMaxOpenConns(),
-		Password:     pgConf.GetPassword(),
-		Port:         pgConf.GetPort(),
-		Username:     pgConf.GetUser(),
-		SSLMode:      pgConf.GetSSLMode(),
-		PingTimeout:  pgConf.GetPingTimeout(),
-	}
-	err := p.Init()
+	p, err := ConnectPostgres(context.Background(), pgConf, nil)
 	if err != nil {
 		panic(err)
 	}
-	_pg = &p
+	_pg.Store(p)
+}
+
+// ConnectPostgres 按重试策略连接 Postgres，policy 为 nil 时只尝试一次
+func ConnectPostgres(ctx context.Context, pgConf *config.Postgres, policy *config.Retry) (*db.Postgres, error) {
+	p := newPostgres(pgConf, pgConf.Replicas)
+	if err := Retry(ctx, policy, StorePostgres, p.Init); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 func GetPostgresDB() *db.Postgres {
-	return _pg
-}
\ No newline at end of file
+	return _pg.Load()
+}
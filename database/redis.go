@@ -1,20 +1,57 @@
 package database
 
 import (
+	"context"
+	"sync/atomic"
+
 	"github.com/zhangc-zwl/thunder/config"
 	"github.com/zhangc-zwl/thunder/db"
 )
 
 var (
+	// RedisCli 由 InitRedis 和 Manager.Init 在启动时同步赋值，降级模式下后台重连成功后不会更新
+	//
+	// Deprecated: 使用 GetRedis，它总是返回最新的连接并且可以在任意 goroutine 中安全读取
 	RedisCli *db.Redis
+
+	_redis atomic.Pointer[db.Redis]
 )
 
+// InitRedis 连接 Redis，失败时 panic，需要处理错误时使用 ConnectRedis 或 Manager
 func InitRedis(redisConf *config.Redis) {
 	if redisConf == nil {
 		return
 	}
+	r, err := ConnectRedis(context.Background(), redisConf, nil)
+	if err != nil {
+		panic(err)
+	}
+	_redis.Store(r)
+	RedisCli = r
+}
 
-	r := db.Redis{}
-	r.Init(redisConf)
-	RedisCli = &r
-}
\ No newline at end of file
+// GetRedis 获取全局 Redis 连接，尚未连接时返回 nil
+// 没有通过 InitRedis、Manager 或 SetRedis 连接时兼容直接给 RedisCli 赋值的写法
+func GetRedis() *db.Redis {
+	if r := _redis.Load(); r != nil {
+		return r
+	}
+	return RedisCli
+}
+
+// SetRedis 设置全局 Redis 连接，一般用于测试或自行创建连接的场景
+func SetRedis(r *db.Redis) {
+	_redis.Store(r)
+}
+
+// ConnectRedis 按重试策略连接 Redis，policy 为 nil 时只尝试一次
+func ConnectRedis(ctx context.Context, redisConf *config.Redis, policy *config.Retry) (*db.Redis, error) {
+	r := &db.Redis{}
+	err := Retry(ctx, policy, StoreRedis, func() error {
+		return r.Init(redisConf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+// Retry 按策略重试 fn，直到成功、达到最大次数或 ctx 结束，返回最后一次的错误
+func Retry(ctx context.Context, policy *config.Retry, name string, fn func() error) error {
+	maxAttempts := policy.GetMaxAttempts()
+	interval := policy.GetInitialInterval()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return err
+		}
+		logs.CtxWarn(ctx, "connect failed, retrying", "store", name, "attempt", attempt, "after", interval, "error", err)
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		interval *= 2
+		if interval > policy.GetMaxInterval() {
+			interval = policy.GetMaxInterval()
+		}
+	}
+}
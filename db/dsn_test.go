@@ -0,0 +1,49 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestMySQLParams(t *testing.T) {
+	m := &MySQL{Host: "127.0.0.1", Port: 3306, Database: "app", Timezone: "Asia/Shanghai", Params: "charset=utf8&readTimeout=3s"}
+	params, err := m.params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := m.dsn(m.Host, m.Port, "root", "pwd", params)
+	want := "root:pwd@tcp(127.0.0.1:3306)/app?charset=utf8&loc=Asia%2FShanghai&parseTime=True&readTimeout=3s"
+	if dsn != want {
+		t.Fatalf("dsn\n got %s\nwant %s", dsn, want)
+	}
+	if _, err := (&MySQL{Params: "%zz"}).params(); err == nil {
+		t.Fatal("want invalid params error")
+	}
+}
+
+func TestPostgresParams(t *testing.T) {
+	p := &Postgres{
+		Database: "app",
+		Params:   "application_name=thunder api",
+		TLS:      &config.TLS{Enable: gptr.Of(true), CaFile: gptr.Of("/etc/ca.pem")},
+	}
+	params, err := p.params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := p.dsn("db", 5432, "u", "p w", params)
+	for _, part := range []string{
+		"password='p w'",
+		"application_name='thunder api'",
+		"TimeZone=Asia/Shanghai",
+		"sslmode=verify-full",
+		"sslrootcert=/etc/ca.pem",
+	} {
+		if !strings.Contains(dsn, part) {
+			t.Fatalf("dsn %q missing %q", dsn, part)
+		}
+	}
+}
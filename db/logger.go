@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger 实现 gorm 的 logger.Interface，SQL 日志通过 logs.FromContext(ctx) 输出，
+// 查询使用 WithContext(ctx) 时日志会带上 request_id 等请求信息
+// 执行出错记录为 ERROR，超过慢查询阈值记录为 WARN，其余 SQL 在 info 级别下记录为 INFO
+type GormLogger struct {
+	level          logger.LogLevel
+	slowThreshold  time.Duration
+	ignoreNotFound bool
+	parameterized  bool
+}
+
+// NewGormLogger 根据配置创建 SQL 日志，conf 为 nil 时使用默认配置
+func NewGormLogger(conf *config.GormLogger) logger.Interface {
+	return &GormLogger{
+		level:          gormLogLevel(conf.GetLevel()),
+		slowThreshold:  conf.GetSlowThreshold(),
+		ignoreNotFound: conf.GetIgnoreNotFound(),
+		parameterized:  conf.GetParameterizedQueries(),
+	}
+}
+
+// gormConfig 返回打开连接使用的配置，cfg 为 nil 或未设置 Logger 时使用 conf 创建的 SQL 日志
+// 调用方传入的 cfg 不会被修改，同一个 cfg 可以在多个连接之间复用
+// gorm.Open 自带的 Ping 失败时不会关闭已创建的连接池，也不受 PingTimeout 限制，
+// 因此总是关闭它，连接检查统一由 Init 中的 PingContext 完成
+func gormConfig(cfg *gorm.Config, conf *config.GormLogger) *gorm.Config {
+	var copied gorm.Config
+	if cfg != nil {
+		copied = *cfg
+	}
+	if copied.Logger == nil {
+		copied.Logger = NewGormLogger(conf)
+	}
+	copied.DisableAutomaticPing = true
+	return &copied
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Info {
+		l.logger(ctx).Info(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Warn {
+		l.logger(ctx).Warn(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Error {
+		l.logger(ctx).Error(fmt.Sprintf(msg, data...), "caller", caller())
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !(l.ignoreNotFound && errors.Is(err, gorm.ErrRecordNotFound)):
+		l.logger(ctx).Error("sql error", l.attrs(fc, elapsed, "error", err)...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		l.logger(ctx).Warn("slow sql", l.attrs(fc, elapsed, "slow_threshold", l.slowThreshold)...)
+	case l.level >= logger.Info:
+		l.logger(ctx).Info("sql", l.attrs(fc, elapsed)...)
+	}
+}
+
+// ParamsFilter 开启 parameterizedQueries 时日志中只保留占位符，不输出参数值
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.parameterized {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (l *GormLogger) attrs(fc func() (string, int64), elapsed time.Duration, extra ...any) []any {
+	sql, rows := fc()
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Duration("duration", elapsed),
+		slog.String("caller", caller()),
+	}
+	// rows 为 -1 表示驱动没有返回影响行数
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	return append(attrs, extra...)
+}
+
+func (l *GormLogger) logger(ctx context.Context) *slog.Logger {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return logs.FromContext(ctx)
+}
+
+// caller 返回业务代码中调用 GORM 的位置，跳过 GORM 和日志适配器自身的调用栈
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") && !strings.Contains(frame.Function, "/thunder/db.(*GormLogger)") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func gormLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGormLoggerTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logs.Init(&config.LogConfig{Format: gptr.Of("json"), Output: &buf})
+	l := NewGormLogger(&config.GormLogger{SlowThreshold: gptr.Of(10 * time.Millisecond)})
+	ctx := logs.WithContext(context.Background(), "request_id", "r1")
+	sql := func() (string, int64) { return "SELECT 1", 1 }
+
+	cases := []struct {
+		begin time.Time
+		err   error
+		level string
+		msg   string
+	}{
+		{time.Now(), nil, "INFO", "sql"},
+		{time.Now().Add(-time.Second), nil, "WARN", "slow sql"},
+		{time.Now(), errors.New("boom"), "ERROR", "sql error"},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		l.Trace(ctx, c.begin, sql, c.err)
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("decode %q: %v", buf.String(), err)
+		}
+		if entry["level"] != c.level || entry["msg"] != c.msg || entry["sql"] != "SELECT 1" || entry["request_id"] != "r1" {
+			t.Fatalf("unexpected entry %v", entry)
+		}
+	}
+
+	buf.Reset()
+	l.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	if bytes.Contains(buf.Bytes(), []byte("sql error")) {
+		t.Fatalf("record not found should not be logged as error, got %s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("logger_test.go")) {
+		t.Fatalf("caller should point to the test, got %s", buf.String())
+	}
+}
+
+func TestGormConfigLogger(t *testing.T) {
+	conf := &config.GormLogger{Level: gptr.Of("warn")}
+	if cfg := gormConfig(nil, conf); cfg.Logger == nil {
+		t.Fatal("nil config should get the configured logger")
+	}
+	// 调用方设置了 Logger 时原样使用
+	custom := logger.Discard
+	cfg := &gorm.Config{Logger: custom, PrepareStmt: true}
+	if got := gormConfig(cfg, conf); got.Logger != custom || !got.PrepareStmt {
+		t.Fatal("caller's logger should not be replaced")
+	}
+	// 未设置 Logger 时使用配置的日志，但不修改调用方的配置
+	shared := &gorm.Config{PrepareStmt: true}
+	got := gormConfig(shared, conf)
+	if _, ok := got.Logger.(*GormLogger); !ok || !got.PrepareStmt {
+		t.Fatalf("expected GormLogger with caller options, got %#v", got.Logger)
+	}
+	if shared.Logger != nil {
+		t.Fatal("caller's config should not be modified")
+	}
+	// gorm.Open 自带的 Ping 失败时会泄漏连接池，统一由 Init 中的 PingContext 检查
+	for _, got := range []*gorm.Config{gormConfig(nil, conf), gormConfig(cfg, conf), gormConfig(shared, conf)} {
+		if !got.DisableAutomaticPing {
+			t.Fatal("automatic ping should be disabled")
+		}
+	}
+	if cfg.DisableAutomaticPing || shared.DisableAutomaticPing {
+		t.Fatal("caller's config should not be modified")
+	}
+}
@@ -3,12 +3,13 @@ package db
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/zhangc-zwl/thunder/config"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"log"
-	"os"
-	"time"
 )
 
 type MySQL struct {
@@ -21,29 +22,29 @@ type MySQL struct {
 	PingTimeout  time.Duration
 	MaxIdleConns int
 	MaxOpenConns int
-	GormDB       *gorm.DB
+	// ConnMaxLifetime、ConnMaxIdleTime 为 0 时不限制
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// Params 附加的 DSN 参数，格式为 a=1&b=2，同名时覆盖默认的 charset、parseTime 和 loc
+	Params string
+	// Timezone 对应 DSN 中的 loc，为空时使用 Local
+	Timezone string
+	TLS      *config.TLS
+	// Logger GormConfig 为 nil 或未设置 Logger 时使用的 SQL 日志配置
+	Logger *config.GormLogger
+	// Replicas 只读从库，配置后读请求路由到从库
+	Replicas []Replica
+	GormDB   *gorm.DB
 }
 
 func (m *MySQL) Init() error {
-	dsn := "%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local"
-	dsn = fmt.Sprintf(dsn, m.Username, m.Password, m.Host, m.Port, m.Database)
-
-	if m.GormConfig == nil {
-		newLogger := logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-			logger.Config{
-				SlowThreshold:             time.Second, // Slow SQL threshold
-				LogLevel:                  logger.Info, // Log level
-				IgnoreRecordNotFoundError: true,        // Ignore ErrRecordNotFound error for logger
-				ParameterizedQueries:      true,        // Don't include params in the SQL logs
-				Colorful:                  false,       // Disable color
-			},
-		)
-		m.GormConfig = &gorm.Config{
-			Logger: newLogger,
-		}
+	params, err := m.params()
+	if err != nil {
+		return err
 	}
-	db, err := gorm.Open(mysql.Open(dsn), m.GormConfig)
+	dsn := m.dsn(m.Host, m.Port, m.Username, m.Password, params)
+
+	db, err := gorm.Open(mysql.Open(dsn), gormConfig(m.GormConfig, m.Logger))
 	if err != nil {
 		return err
 	}
@@ -56,13 +57,83 @@ func (m *MySQL) Init() error {
 	defer cancel()
 	err = conn.PingContext(ctx)
 	if err != nil {
+		// 重试时会重新 Open，关闭本次创建的连接池
+		_ = conn.Close()
 		return err
 	}
-	if m.MaxIdleConns != 0 {
-		conn.SetMaxIdleConns(m.MaxIdleConns)
-	}
-	if m.MaxOpenConns != 0 {
-		conn.SetMaxOpenConns(m.MaxOpenConns)
+	pool := m.pool()
+	pool.apply(conn)
+	if len(m.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(m.Replicas))
+		for _, r := range m.Replicas {
+			replicas = append(replicas, mysql.Open(m.dsn(r.Host, r.Port, r.Username, r.Password, params)))
+		}
+		if err := useReplicas(db, replicas, pool); err != nil {
+			_ = conn.Close()
+			return err
+		}
 	}
 	return nil
 }
+
+func (m *MySQL) Ping(ctx context.Context) error {
+	conn, err := m.GormDB.DB()
+	if err != nil {
+		return err
+	}
+	return conn.PingContext(ctx)
+}
+
+func (m *MySQL) Close() error {
+	closeReplicas(m.GormDB)
+	conn, err := m.GormDB.DB()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (m *MySQL) pool() poolConfig {
+	return poolConfig{
+		maxIdleConns:    m.MaxIdleConns,
+		maxOpenConns:    m.MaxOpenConns,
+		connMaxLifetime: m.ConnMaxLifetime,
+		connMaxIdleTime: m.ConnMaxIdleTime,
+	}
+}
+
+// params 合并默认参数、TLS 和自定义参数
+func (m *MySQL) params() (string, error) {
+	timezone := m.Timezone
+	if timezone == "" {
+		timezone = "Local"
+	}
+	values := url.Values{}
+	values.Set("charset", "utf8mb4")
+	values.Set("parseTime", "True")
+	values.Set("loc", timezone)
+	if m.TLS.GetEnable() {
+		tlsConf, err := NewTLSConfig(m.TLS)
+		if err != nil {
+			return "", err
+		}
+		// 同一个 host 的主从库共用证书配置
+		name := fmt.Sprintf("thunder-%s-%d", m.Host, m.Port)
+		if err := mysqldriver.RegisterTLSConfig(name, tlsConf); err != nil {
+			return "", err
+		}
+		values.Set("tls", name)
+	}
+	extra, err := url.ParseQuery(m.Params)
+	if err != nil {
+		return "", fmt.Errorf("mysql: invalid params %q: %w", m.Params, err)
+	}
+	for key, value := range extra {
+		values[key] = value
+	}
+	return values.Encode(), nil
+}
+
+func (m *MySQL) dsn(host string, port int, username, password, params string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", username, password, host, port, m.Database, params)
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// poolConfig 连接池参数，为 0 的项保持 database/sql 的默认值
+type poolConfig struct {
+	maxIdleConns    int
+	maxOpenConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+func (p poolConfig) apply(conn *sql.DB) {
+	if p.maxIdleConns != 0 {
+		conn.SetMaxIdleConns(p.maxIdleConns)
+	}
+	if p.maxOpenConns != 0 {
+		conn.SetMaxOpenConns(p.maxOpenConns)
+	}
+	if p.connMaxLifetime != 0 {
+		conn.SetConnMaxLifetime(p.connMaxLifetime)
+	}
+	if p.connMaxIdleTime != 0 {
+		conn.SetConnMaxIdleTime(p.connMaxIdleTime)
+	}
+}
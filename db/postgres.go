@@ -3,13 +3,14 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/zhangc-zwl/thunder/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 type Postgres struct {
@@ -23,29 +24,30 @@ type Postgres struct {
 	PingTimeout  time.Duration
 	MaxIdleConns int
 	MaxOpenConns int
-	GormDB       *gorm.DB
+	// ConnMaxLifetime、ConnMaxIdleTime 为 0 时不限制
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// Params 附加的 DSN 参数，格式为 a=1&b=2，同名时覆盖默认参数
+	Params string
+	// Timezone 对应 DSN 中的 TimeZone，为空时使用 Asia/Shanghai
+	Timezone string
+	// TLS 启用后证书文件通过 sslrootcert、sslcert、sslkey 传给驱动，sslmode 为 disable 时自动改为 verify-full
+	TLS *config.TLS
+	// Logger GormConfig 为 nil 或未设置 Logger 时使用的 SQL 日志配置
+	Logger *config.GormLogger
+	// Replicas 只读从库，配置后读请求路由到从库
+	Replicas []Replica
+	GormDB   *gorm.DB
 }
 
 func (p *Postgres) Init() error {
-	dsn := "host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai"
-	dsn = fmt.Sprintf(dsn, p.Host, p.Username, p.Password, p.Database, p.Port, p.SSLMode)
-
-	if p.GormConfig == nil {
-		newLogger := logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-			logger.Config{
-				SlowThreshold:             time.Second, // Slow SQL threshold
-				LogLevel:                  logger.Info, // Log level
-				IgnoreRecordNotFoundError: true,        // Ignore ErrRecordNotFound error for logger
-				ParameterizedQueries:      true,        // Don't include params in the SQL logs
-				Colorful:                  false,       // Disable color
-			},
-		)
-		p.GormConfig = &gorm.Config{
-			Logger: newLogger,
-		}
+	params, err := p.params()
+	if err != nil {
+		return err
 	}
-	db, err := gorm.Open(postgres.Open(dsn), p.GormConfig)
+	dsn := p.dsn(p.Host, p.Port, p.Username, p.Password, params)
+
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig(p.GormConfig, p.Logger))
 	if err != nil {
 		return err
 	}
@@ -58,13 +60,108 @@ func (p *Postgres) Init() error {
 	defer cancel()
 	err = conn.PingContext(ctx)
 	if err != nil {
+		// 重试时会重新 Open，关闭本次创建的连接池
+		_ = conn.Close()
 		return err
 	}
-	if p.MaxIdleConns != 0 {
-		conn.SetMaxIdleConns(p.MaxIdleConns)
-	}
-	if p.MaxOpenConns != 0 {
-		conn.SetMaxOpenConns(p.MaxOpenConns)
+	pool := p.pool()
+	pool.apply(conn)
+	if len(p.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(p.Replicas))
+		for _, r := range p.Replicas {
+			replicas = append(replicas, postgres.Open(p.dsn(r.Host, r.Port, r.Username, r.Password, params)))
+		}
+		if err := useReplicas(db, replicas, pool); err != nil {
+			_ = conn.Close()
+			return err
+		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (p *Postgres) Ping(ctx context.Context) error {
+	conn, err := p.GormDB.DB()
+	if err != nil {
+		return err
+	}
+	return conn.PingContext(ctx)
+}
+
+func (p *Postgres) Close() error {
+	closeReplicas(p.GormDB)
+	conn, err := p.GormDB.DB()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *Postgres) pool() poolConfig {
+	return poolConfig{
+		maxIdleConns:    p.MaxIdleConns,
+		maxOpenConns:    p.MaxOpenConns,
+		connMaxLifetime: p.ConnMaxLifetime,
+		connMaxIdleTime: p.ConnMaxIdleTime,
+	}
+}
+
+// params 合并默认参数、TLS 和自定义参数，返回 key=value 形式的 DSN 片段
+func (p *Postgres) params() (string, error) {
+	timezone := p.Timezone
+	if timezone == "" {
+		timezone = "Asia/Shanghai"
+	}
+	sslMode := p.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	values := map[string]string{"TimeZone": timezone}
+	if p.TLS.GetEnable() {
+		if sslMode == "disable" {
+			sslMode = "verify-full"
+			if p.TLS.GetInsecureSkipVerify() {
+				sslMode = "require"
+			}
+		}
+		if caFile := p.TLS.GetCaFile(); caFile != "" {
+			values["sslrootcert"] = caFile
+		}
+		if certFile := p.TLS.GetCertFile(); certFile != "" {
+			values["sslcert"] = certFile
+			values["sslkey"] = p.TLS.GetKeyFile()
+		}
+	}
+	values["sslmode"] = sslMode
+	extra, err := url.ParseQuery(p.Params)
+	if err != nil {
+		return "", fmt.Errorf("postgres: invalid params %q: %w", p.Params, err)
+	}
+	for key := range extra {
+		values[key] = extra.Get(key)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+quotePgValue(values[key]))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (p *Postgres) dsn(host string, port int, username, password, params string) string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d %s",
+		quotePgValue(host), quotePgValue(username), quotePgValue(password), quotePgValue(p.Database), port, params)
+}
+
+// quotePgValue 值中包含空格、引号或反斜杠时按 libpq 的规则加单引号转义
+func quotePgValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
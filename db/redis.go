@@ -2,33 +2,80 @@ package db
 
 import (
 	"context"
-	"github.com/zhangc-zwl/thunder/config"
-	"github.com/redis/go-redis/v9"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/config"
 )
 
 type Redis struct {
-	Options *redis.Options
-	Client  *redis.Client
+	// Mode 为 standalone、sentinel 或 cluster，为空时使用配置中的 mode
+	Mode    string
+	Options *redis.UniversalOptions
+	// PingTimeout 为 0 时使用配置中的 pingTimeout
+	PingTimeout time.Duration
+	Client      redis.UniversalClient
 }
 
-func (r *Redis) Init(redisConf *config.Redis) {
+func (r *Redis) Init(redisConf *config.Redis) error {
+	if r.Mode == "" {
+		r.Mode = redisConf.GetMode()
+	}
 	if r.Options == nil {
-		r.Options = &redis.Options{
-			Addr:         redisConf.GetAddr(),
-			DB:           redisConf.GetDB(),
-			Password:     redisConf.GetPassword(),
-			PoolSize:     redisConf.GetPoolSize(),
-			MaxIdleConns: redisConf.GetMaxIdleConns(),
-			MaxActiveConns: redisConf.GetMaxOpenConns(),
+		tlsConf, err := NewTLSConfig(redisConf.GetTLS())
+		if err != nil {
+			return err
+		}
+		r.Options = &redis.UniversalOptions{
+			Addrs:            redisConf.GetAddrs(),
+			MasterName:       redisConf.GetMasterName(),
+			DB:               redisConf.GetDB(),
+			Username:         redisConf.GetUsername(),
+			Password:         redisConf.GetPassword(),
+			SentinelUsername: redisConf.GetSentinelUsername(),
+			SentinelPassword: redisConf.GetSentinelPassword(),
+			PoolSize:         redisConf.GetPoolSize(),
+			MaxIdleConns:     redisConf.GetMaxIdleConns(),
+			MaxActiveConns:   redisConf.GetMaxOpenConns(),
+			ConnMaxIdleTime:  time.Duration(redisConf.GetIdleTimeout()) * time.Second,
+			TLSConfig:        tlsConf,
 		}
+		if r.Mode == config.RedisModeStandalone {
+			r.Options.Addrs = []string{redisConf.GetAddr()}
+		}
+	}
+	if r.PingTimeout == 0 {
+		r.PingTimeout = redisConf.GetPingTimeout()
 	}
-	rdb := redis.NewClient(r.Options)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	rdb := newUniversalClient(r.Mode, r.Options)
+	ctx, cancel := context.WithTimeout(context.Background(), r.PingTimeout)
 	defer cancel()
 	_, err := rdb.Ping(ctx).Result()
 	if err != nil {
-		panic(err)
+		_ = rdb.Close()
+		return err
 	}
 	r.Client = rdb
-}
\ No newline at end of file
+	return nil
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.Client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.Client.Close()
+}
+
+// newUniversalClient 按 mode 创建客户端，不依赖 redis.NewUniversalClient 根据地址数量推断拓扑，
+// 避免只配置了一个入口地址的集群被当作单节点连接
+func newUniversalClient(mode string, opts *redis.UniversalOptions) redis.UniversalClient {
+	switch mode {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	case config.RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster())
+	default:
+		return redis.NewClient(opts.Simple())
+	}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Replica 只读从库的连接信息，数据库名和其他参数与主库一致
+type Replica struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+type stickyKey struct{}
+
+type sticky struct {
+	written atomic.Bool
+}
+
+// WithSticky 返回开启写后读主库的 context，在该 context 中执行过写操作后，后续的读请求都路由到主库，
+// 避免主从延迟导致读不到刚写入的数据，一般在请求开始时调用一次
+func WithSticky(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(stickyKey{}).(*sticky); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, stickyKey{}, &sticky{})
+}
+
+// ForcePrimary 返回所有读请求都使用主库的 context
+func ForcePrimary(ctx context.Context) context.Context {
+	s := &sticky{}
+	s.written.Store(true)
+	return context.WithValue(ctx, stickyKey{}, s)
+}
+
+// UsePrimary 当前 context 的读请求是否需要使用主库
+func UsePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	s, ok := ctx.Value(stickyKey{}).(*sticky)
+	return ok && s.written.Load()
+}
+
+// useReplicas 注册读写分离，读请求随机路由到从库，写请求和事务使用主库
+func useReplicas(gdb *gorm.DB, replicas []gorm.Dialector, pool poolConfig) error {
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	})
+	if err := gdb.Use(resolver); err != nil {
+		return err
+	}
+	if pool.maxIdleConns != 0 {
+		resolver.SetMaxIdleConns(pool.maxIdleConns)
+	}
+	if pool.maxOpenConns != 0 {
+		resolver.SetMaxOpenConns(pool.maxOpenConns)
+	}
+	if pool.connMaxLifetime != 0 {
+		resolver.SetConnMaxLifetime(pool.connMaxLifetime)
+	}
+	if pool.connMaxIdleTime != 0 {
+		resolver.SetConnMaxIdleTime(pool.connMaxIdleTime)
+	}
+	return registerSticky(gdb)
+}
+
+// closeReplicas 关闭 dbresolver 持有的所有连接池，其中也包含主库，sql.DB.Close 可以重复调用
+func closeReplicas(gdb *gorm.DB) {
+	plugin, ok := gdb.Config.Plugins["gorm:db_resolver"]
+	if !ok {
+		return
+	}
+	if resolver, ok := plugin.(*dbresolver.DBResolver); ok {
+		_ = resolver.Call(func(connPool gorm.ConnPool) error {
+			if conn, ok := connPool.(interface{ Close() error }); ok {
+				_ = conn.Close()
+			}
+			return nil
+		})
+	}
+}
+
+// registerSticky 注册写后读主库的回调，读回调与 dbresolver 一样使用 Before("*")，
+// 后注册的回调排在前面，因此必须在 dbresolver 之后调用
+func registerSticky(gdb *gorm.DB) error {
+	cb := gdb.Callback()
+	if err := cb.Query().Before("*").Register("thunder:sticky_read", stickyRead); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("*").Register("thunder:sticky_read", stickyRead); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("thunder:sticky_read", stickyRead); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("thunder:sticky_write", stickyWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("thunder:sticky_write", stickyWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("thunder:sticky_write", stickyWrite); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("thunder:sticky_write", stickyRawWrite)
+}
+
+func stickyRead(db *gorm.DB) {
+	if UsePrimary(db.Statement.Context) {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
+
+func stickyWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if s, ok := db.Statement.Context.Value(stickyKey{}).(*sticky); ok {
+		s.written.Store(true)
+	}
+}
+
+// Exec 执行的 SQL 不是 SELECT 时视为写操作
+func stickyRawWrite(db *gorm.DB) {
+	sql := strings.TrimSpace(db.Statement.SQL.String())
+	if len(sql) >= 6 && strings.EqualFold(sql[:6], "select") {
+		return
+	}
+	stickyWrite(db)
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	"github.com/zhangc-zwl/thunder/config"
+)
+
+// NewTLSConfig 根据配置构建客户端 TLS 配置，未启用时返回 nil
+func NewTLSConfig(conf *config.TLS) (*tls.Config, error) {
+	if !conf.GetEnable() {
+		return nil, nil
+	}
+	tlsConf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         conf.GetServerName(),
+		InsecureSkipVerify: conf.GetInsecureSkipVerify(),
+	}
+	if caFile := conf.GetCaFile(); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("tls: no certificate found in " + caFile)
+		}
+		tlsConf.RootCAs = pool
+	}
+	if conf.GetCertFile() != "" {
+		cert, err := tls.LoadX509KeyPair(conf.GetCertFile(), conf.GetKeyFile())
+		if err != nil {
+			return nil, err
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConf, nil
+}
@@ -2,8 +2,11 @@ package errs
 
 var ErrParam = NewError(400, "param error")
 var ErrUnauthorized = NewError(401, "unauthorized")
+var ErrForbidden = NewError(403, "forbidden")
 var NoEventHandler = NewError(500, "no handler")
 var DBError = NewError(999, "db error")
+var ErrInternal = NewError(500, "internal server error")
+var ErrTooManyRequests = NewError(429, "too many requests")
 
 type Errors struct {
 	Code int    `json:"code"`
@@ -0,0 +1,440 @@
+package gorms
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/res"
+	"github.com/zhangc-zwl/thunder/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+
+	// auditMask 标记为 audit:"mask" 的字段和加密字段在变更记录中的值
+	auditMask = "******"
+
+	auditOldKey = "thunder:audit_old"
+)
+
+// AuditTable 变更记录表名
+var AuditTable = "audit_logs"
+
+// Model 带审计列和软删除的基础模型，Delete 时只设置 deleted_at，查询自动过滤已删除数据，
+// 需要查询或物理删除已删除数据时使用 Unscoped
+//
+//	type Order struct {
+//		gorms.Model
+//		Amount types.Money
+//	}
+type Model struct {
+	ID        int64          `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy string         `gorm:"size:64" json:"createdBy"`
+	UpdatedBy string         `gorm:"size:64" json:"updatedBy"`
+}
+
+// Auditable 实现该接口且返回 true 的模型记录字段级变更历史，
+// 字段标签 audit:"-" 不记录该字段，audit:"mask" 只记录是否变更，types.EncryptedString 字段自动按 mask 处理
+//
+//	func (User) Auditable() bool { return true }
+type Auditable interface {
+	Auditable() bool
+}
+
+// Change 字段变更前后的值，创建时只有 New，删除时只有 Old
+type Change struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// AuditLog 变更记录，Entity 为表名，EntityID 为主键，联合主键以逗号拼接
+type AuditLog struct {
+	ID        int64                         `gorm:"primaryKey" json:"id"`
+	Entity    string                        `gorm:"size:64;index:idx_audit_entity,priority:1" json:"entity"`
+	EntityID  string                        `gorm:"size:64;index:idx_audit_entity,priority:2" json:"entityId"`
+	Action    string                        `gorm:"size:16" json:"action"`
+	Changes   types.JSON[map[string]Change] `json:"changes"`
+	UserID    string                        `gorm:"size:64" json:"userId"`
+	CreatedAt time.Time                     `json:"createdAt"`
+}
+
+func (AuditLog) TableName() string {
+	return AuditTable
+}
+
+// AuditPlugin 审计插件：
+// 创建时用 context 中的当前用户（req.UserId）填充 created_by、updated_by，更新和软删除时填充 updated_by；
+// 实现 Auditable 的模型在创建、更新、删除时把变更字段写入 AuditTable，和业务操作在同一个连接上执行，
+// 在事务中时随事务一起提交或回滚。Raw 和 Exec 执行的 SQL 不受影响
+//
+//	gdb.Use(&gorms.AuditPlugin{})
+//	gdb.AutoMigrate(&gorms.AuditLog{})
+//
+// 批量更新和删除会先查出所有受影响的行用于对比，大批量操作前需要注意数据量
+type AuditPlugin struct {
+	// CreatedBy 创建人列名，默认 created_by
+	CreatedBy string
+	// UpdatedBy 更新人列名，默认 updated_by
+	UpdatedBy string
+}
+
+func (p *AuditPlugin) Name() string {
+	return "thunder:audit"
+}
+
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	if p.CreatedBy == "" {
+		p.CreatedBy = "created_by"
+	}
+	if p.UpdatedBy == "" {
+		p.UpdatedBy = "updated_by"
+	}
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("thunder:audit_before_create", p.beforeCreate); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("thunder:audit_after_create", p.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("thunder:audit_before_update", p.beforeUpdate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("thunder:audit_after_update", p.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("thunder:audit_before_delete", p.beforeDelete); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("thunder:audit_after_delete", p.afterDelete)
+}
+
+func (p *AuditPlugin) beforeCreate(db *gorm.DB) {
+	stmt := db.Statement
+	userId := req.UserId(stmt.Context)
+	if db.Error != nil || stmt.Schema == nil || userId == "" {
+		return
+	}
+	for _, name := range []string{p.CreatedBy, p.UpdatedBy} {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+		eachStruct(stmt.ReflectValue, func(rv reflect.Value) {
+			if _, zero := field.ValueOf(stmt.Context, rv); zero {
+				_ = db.AddError(field.Set(stmt.Context, rv, userId))
+			}
+		})
+	}
+}
+
+func (p *AuditPlugin) afterCreate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || !auditable(stmt) {
+		return
+	}
+	var logs []*AuditLog
+	eachStruct(stmt.ReflectValue, func(rv reflect.Value) {
+		changes := make(map[string]Change)
+		for name, value := range snapshot(stmt, rv) {
+			if value != nil {
+				changes[name] = change(stmt, name, nil, value)
+			}
+		}
+		logs = append(logs, p.newLog(stmt, AuditCreate, entityId(stmt, rv), changes))
+	})
+	p.write(db, logs)
+}
+
+func (p *AuditPlugin) beforeUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	if userId := req.UserId(stmt.Context); userId != "" && stmt.Schema.LookUpField(p.UpdatedBy) != nil {
+		stmt.SetColumn(p.UpdatedBy, userId, true)
+	}
+	p.loadOld(db)
+}
+
+func (p *AuditPlugin) afterUpdate(db *gorm.DB) {
+	olds, ok := p.olds(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	news, err := p.query(db, olds)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	var logs []*AuditLog
+	for _, old := range olds {
+		current, ok := news[old.id]
+		if !ok {
+			continue
+		}
+		changes := make(map[string]Change)
+		for name, value := range current.values {
+			if before := old.values[name]; !reflect.DeepEqual(before, value) {
+				changes[name] = change(stmt, name, before, value)
+			}
+		}
+		if len(changes) > 0 {
+			logs = append(logs, p.newLog(stmt, AuditUpdate, old.id, changes))
+		}
+	}
+	p.write(db, logs)
+}
+
+// beforeDelete 软删除时同时填充 updated_by。软删除的 UPDATE 语句原本在 gorm:delete 中生成且只设置 deleted_at，
+// 这里提前生成并把更新人加入 SET，gorm:delete 发现语句已生成时直接执行
+func (p *AuditPlugin) beforeDelete(db *gorm.DB) {
+	p.loadOld(db)
+	stmt := db.Statement
+	userId := req.UserId(stmt.Context)
+	if db.Error != nil || stmt.Schema == nil || stmt.Unscoped || stmt.SQL.Len() > 0 || userId == "" {
+		return
+	}
+	field := stmt.Schema.LookUpField(p.UpdatedBy)
+	if field == nil {
+		return
+	}
+	for _, c := range stmt.Schema.DeleteClauses {
+		if _, ok := c.(gorm.SoftDeleteDeleteClause); !ok {
+			continue
+		}
+		stmt.AddClause(c)
+		set, _ := stmt.Clauses["SET"].Expression.(clause.Set)
+		stmt.AddClause(append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: userId}))
+		stmt.SetColumn(field.DBName, userId, true)
+		stmt.SQL.Reset()
+		stmt.Vars = nil
+		stmt.Build(db.Callback().Update().Clauses...)
+		return
+	}
+}
+
+func (p *AuditPlugin) afterDelete(db *gorm.DB) {
+	olds, ok := p.olds(db)
+	if !ok {
+		return
+	}
+	logs := make([]*AuditLog, 0, len(olds))
+	for _, old := range olds {
+		changes := make(map[string]Change, len(old.values))
+		for name, value := range old.values {
+			if value != nil {
+				changes[name] = change(db.Statement, name, value, nil)
+			}
+		}
+		logs = append(logs, p.newLog(db.Statement, AuditDelete, old.id, changes))
+	}
+	p.write(db, logs)
+}
+
+// auditRow 受影响行的主键和字段快照
+type auditRow struct {
+	id     string
+	pk     []any
+	values map[string]any
+}
+
+// loadOld 在更新、删除前按相同的条件查出受影响的行，没有条件的全表操作交给 GORM 报 ErrMissingWhereClause
+func (p *AuditPlugin) loadOld(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || !auditable(stmt) {
+		return
+	}
+	where, hasWhere := stmt.Clauses["WHERE"]
+	var pkExpr clause.Expression
+	if stmt.ReflectValue.IsValid() && len(stmt.Schema.PrimaryFields) > 0 {
+		_, values := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		if len(values) > 0 {
+			column, queryValues := schema.ToQueryValues(clause.CurrentTable, stmt.Schema.PrimaryFieldDBNames, values)
+			pkExpr = clause.IN{Column: column, Values: queryValues}
+		}
+	}
+	if !hasWhere && pkExpr == nil && !db.AllowGlobalUpdate {
+		return
+	}
+	tx := p.session(db)
+	if stmt.Unscoped {
+		tx = tx.Unscoped()
+	}
+	if hasWhere {
+		tx = tx.Clauses(where.Expression)
+	}
+	if pkExpr != nil {
+		tx = tx.Clauses(pkExpr)
+	}
+	rows, err := p.find(tx, stmt)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	db.InstanceSet(auditOldKey, rows)
+}
+
+func (p *AuditPlugin) olds(db *gorm.DB) ([]*auditRow, bool) {
+	if db.Error != nil {
+		return nil, false
+	}
+	value, ok := db.InstanceGet(auditOldKey)
+	if !ok {
+		return nil, false
+	}
+	rows, _ := value.([]*auditRow)
+	return rows, len(rows) > 0
+}
+
+// query 更新后按主键重新查询，更新可能修改了 deleted_at，所以不过滤软删除
+func (p *AuditPlugin) query(db *gorm.DB, olds []*auditRow) (map[string]*auditRow, error) {
+	stmt := db.Statement
+	values := make([][]any, len(olds))
+	for i, old := range olds {
+		values[i] = old.pk
+	}
+	column, queryValues := schema.ToQueryValues(clause.CurrentTable, stmt.Schema.PrimaryFieldDBNames, values)
+	tx := p.session(db).Unscoped().Clauses(clause.IN{Column: column, Values: queryValues})
+	rows, err := p.find(tx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*auditRow, len(rows))
+	for _, row := range rows {
+		result[row.id] = row
+	}
+	return result, nil
+}
+
+func (p *AuditPlugin) find(tx *gorm.DB, stmt *gorm.Statement) ([]*auditRow, error) {
+	list := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := tx.Table(stmt.Table).Find(list.Interface()).Error; err != nil {
+		return nil, err
+	}
+	list = list.Elem()
+	rows := make([]*auditRow, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		rv := list.Index(i)
+		pk := make([]any, len(stmt.Schema.PrimaryFields))
+		for j, field := range stmt.Schema.PrimaryFields {
+			pk[j], _ = field.ValueOf(stmt.Context, rv)
+		}
+		rows = append(rows, &auditRow{id: entityId(stmt, rv), pk: pk, values: snapshot(stmt, rv)})
+	}
+	return rows, nil
+}
+
+// session 在同一个连接上执行额外的查询和写入，在事务中时使用同一个事务
+func (p *AuditPlugin) session(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+}
+
+func (p *AuditPlugin) newLog(stmt *gorm.Statement, action, id string, changes map[string]Change) *AuditLog {
+	return &AuditLog{
+		Entity:   stmt.Table,
+		EntityID: id,
+		Action:   action,
+		Changes:  types.NewJSON(changes),
+		UserID:   req.UserId(stmt.Context),
+	}
+}
+
+func (p *AuditPlugin) write(db *gorm.DB, logs []*AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := p.session(db).Create(&logs).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("gorms: write audit log: %w", err))
+	}
+}
+
+func auditable(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	a, ok := reflect.New(stmt.Schema.ModelType).Interface().(Auditable)
+	return ok && a.Auditable()
+}
+
+var encryptedType = reflect.TypeOf(types.EncryptedString(""))
+
+// snapshot 返回需要记录的字段值，零值记为 nil，自动维护的时间字段不记录
+func snapshot(stmt *gorm.Statement, rv reflect.Value) map[string]any {
+	values := make(map[string]any)
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || field.Tag.Get("audit") == "-" || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			continue
+		}
+		value, zero := field.ValueOf(stmt.Context, rv)
+		if zero {
+			value = nil
+		}
+		values[field.DBName] = value
+	}
+	return values
+}
+
+// change 返回写入变更记录的值，需要掩码的字段只记录是否有值
+func change(stmt *gorm.Statement, name string, old, new any) Change {
+	field := stmt.Schema.LookUpField(name)
+	if field == nil || (field.Tag.Get("audit") != "mask" && field.FieldType != encryptedType) {
+		return Change{Old: old, New: new}
+	}
+	if old != nil {
+		old = auditMask
+	}
+	if new != nil {
+		new = auditMask
+	}
+	return Change{Old: old, New: new}
+}
+
+func entityId(stmt *gorm.Statement, rv reflect.Value) string {
+	ids := make([]string, len(stmt.Schema.PrimaryFields))
+	for i, field := range stmt.Schema.PrimaryFields {
+		value, _ := field.ValueOf(stmt.Context, rv)
+		ids[i] = fmt.Sprint(value)
+	}
+	return strings.Join(ids, ",")
+}
+
+func eachStruct(rv reflect.Value, fn func(reflect.Value)) {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fn(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		fn(rv)
+	}
+}
+
+// AuditHistory 分页查询 T 中某条记录的变更历史，按时间倒序，List 为 []*AuditLog
+//
+//	page, err := gorms.AuditHistory[User](ctx, nil, userId, pageInfo)
+func AuditHistory[T any](ctx context.Context, db *gorm.DB, id any, p req.PageInfo) (*res.Page, error) {
+	db = Use(ctx, db)
+	if db == nil {
+		return nil, ErrNoDB
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return Paginate[AuditLog](ctx, db, p, Where("entity = ? AND entity_id = ?", stmt.Table, fmt.Sprint(id)), func(db *gorm.DB) *gorm.DB {
+		return db.Order("id DESC")
+	})
+}
@@ -0,0 +1,215 @@
+package gorms
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type auditUser struct {
+	Model
+	Name     string
+	Password string `audit:"-"`
+	Phone    string `audit:"mask"`
+	IdCard   types.EncryptedString
+}
+
+func (auditUser) Auditable() bool { return true }
+
+func TestAuditSnapshot(t *testing.T) {
+	s, err := schema.Parse(&auditUser{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := &gorm.Statement{Schema: s, Context: context.Background()}
+	if !auditable(stmt) {
+		t.Fatal("auditUser should be auditable")
+	}
+	user := reflect.ValueOf(auditUser{Model: Model{ID: 7}, Name: "a", Password: "secret", Phone: "123"})
+	values := snapshot(stmt, user)
+	if _, ok := values["password"]; ok {
+		t.Fatal(`audit:"-" field should be skipped`)
+	}
+	if _, ok := values["created_at"]; ok {
+		t.Fatal("auto time field should be skipped")
+	}
+	if values["name"] != "a" || values["id_card"] != nil {
+		t.Fatalf("unexpected snapshot %v", values)
+	}
+	if c := change(stmt, "phone", nil, values["phone"]); c.Old != nil || c.New != auditMask {
+		t.Fatalf("phone should be masked, got %+v", c)
+	}
+	if c := change(stmt, "id_card", "x", nil); c.Old != auditMask {
+		t.Fatalf("encrypted field should be masked, got %+v", c)
+	}
+	if id := entityId(stmt, user); id != "7" {
+		t.Fatalf("entity id = %q", id)
+	}
+}
+
+func TestUserIdContext(t *testing.T) {
+	if req.UserId(context.Background()) != "" {
+		t.Fatal("background context should have no user")
+	}
+	if got := req.UserId(req.WithUserId(context.Background(), "u1")); got != "u1" {
+		t.Fatalf("user id = %q", got)
+	}
+}
+
+// auditDB 注册审计插件的 sqlite 数据库，同时记录 SQL
+func auditDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	db, rec := recordDB(t, &auditUser{}, &AuditLog{})
+	if err := db.Use(&AuditPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, rec
+}
+
+func auditLogs(t *testing.T, db *gorm.DB, action string) []AuditLog {
+	t.Helper()
+	var logs []AuditLog
+	if err := db.Where("action = ?", action).Order("id").Find(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
+
+func TestAuditCreate(t *testing.T) {
+	db, _ := auditDB(t)
+	ctx := req.WithUserId(context.Background(), "u1")
+	user := &auditUser{Name: "a", Password: "secret", Phone: "123"}
+	if err := db.WithContext(ctx).Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.CreatedBy != "u1" || user.UpdatedBy != "u1" {
+		t.Fatalf("created_by = %q, updated_by = %q", user.CreatedBy, user.UpdatedBy)
+	}
+	logs := auditLogs(t, db, AuditCreate)
+	if len(logs) != 1 {
+		t.Fatalf("want 1 create log, got %d", len(logs))
+	}
+	log := logs[0]
+	changes := log.Changes.Data
+	if log.Entity != "audit_users" || log.EntityID != "1" || log.UserID != "u1" {
+		t.Fatalf("unexpected log %+v", log)
+	}
+	if changes["name"].New != "a" || changes["phone"].New != auditMask {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	if _, ok := changes["password"]; ok {
+		t.Fatal(`audit:"-" field should not be logged`)
+	}
+}
+
+func TestAuditUpdate(t *testing.T) {
+	db, rec := auditDB(t)
+	user := &auditUser{Name: "a"}
+	if err := db.WithContext(req.WithUserId(context.Background(), "u1")).Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := req.WithUserId(context.Background(), "u2")
+	if err := db.WithContext(ctx).Model(user).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	var update string
+	for _, sql := range rec.sqls {
+		if strings.HasPrefix(sql, "UPDATE") {
+			update = sql
+		}
+	}
+	if !strings.Contains(update, "`updated_by`=\"u2\"") {
+		t.Fatalf("update should set updated_by: %s", update)
+	}
+	var got auditUser
+	if err := db.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "b" || got.CreatedBy != "u1" || got.UpdatedBy != "u2" {
+		t.Fatalf("unexpected row %+v", got)
+	}
+	logs := auditLogs(t, db, AuditUpdate)
+	if len(logs) != 1 {
+		t.Fatalf("want 1 update log, got %d", len(logs))
+	}
+	changes := logs[0].Changes.Data
+	if c := changes["name"]; c.Old != "a" || c.New != "b" {
+		t.Fatalf("unexpected name change %+v", c)
+	}
+	if c := changes["updated_by"]; c.Old != "u1" || c.New != "u2" {
+		t.Fatalf("unexpected updated_by change %+v", c)
+	}
+	if logs[0].UserID != "u2" {
+		t.Fatalf("log user = %q", logs[0].UserID)
+	}
+	// 没有变化的更新不记录
+	if err := db.WithContext(ctx).Model(user).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(auditLogs(t, db, AuditUpdate)); n != 1 {
+		t.Fatalf("unchanged update should not be logged, got %d logs", n)
+	}
+}
+
+func TestAuditDelete(t *testing.T) {
+	db, rec := auditDB(t)
+	users := []*auditUser{{Name: "a"}, {Name: "b"}}
+	if err := db.WithContext(req.WithUserId(context.Background(), "u1")).Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := req.WithUserId(context.Background(), "u2")
+	if err := db.WithContext(ctx).Delete(users[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	var deleteSQL string
+	for _, sql := range rec.sqls {
+		if strings.HasPrefix(sql, "UPDATE `audit_users` SET `deleted_at`") {
+			deleteSQL = sql
+		}
+	}
+	if !strings.Contains(deleteSQL, "`updated_by`=\"u2\"") || !strings.Contains(deleteSQL, "`audit_users`.`id` = 1") {
+		t.Fatalf("soft delete should set updated_by on the deleted row only: %s", deleteSQL)
+	}
+	if users[0].UpdatedBy != "u2" || !users[0].DeletedAt.Valid {
+		t.Fatalf("deleted model should be updated, got %+v", users[0].Model)
+	}
+	var got auditUser
+	if err := db.Unscoped().First(&got, users[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.UpdatedBy != "u2" || !got.DeletedAt.Valid {
+		t.Fatalf("unexpected soft deleted row %+v", got.Model)
+	}
+	var other auditUser
+	if err := db.First(&other, users[1].ID).Error; err != nil || other.UpdatedBy != "u1" {
+		t.Fatalf("other rows should be untouched, got %+v %v", other.Model, err)
+	}
+	logs := auditLogs(t, db, AuditDelete)
+	if len(logs) != 1 || logs[0].EntityID != "1" || logs[0].UserID != "u2" || logs[0].Changes.Data["name"].Old != "a" {
+		t.Fatalf("unexpected delete logs %+v", logs)
+	}
+
+	// 没有条件的删除仍然由 GORM 拒绝
+	if err := db.WithContext(ctx).Delete(&auditUser{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("want ErrMissingWhereClause, got %v", err)
+	}
+	// 物理删除不设置 updated_by
+	if err := db.WithContext(ctx).Unscoped().Delete(users[1]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(auditLogs(t, db, AuditDelete)); n != 2 {
+		t.Fatalf("want 2 delete logs, got %d", n)
+	}
+	var count int64
+	if err := db.Unscoped().Model(&auditUser{}).Where("id = ?", users[1].ID).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("row should be removed, count %d %v", count, err)
+	}
+}
@@ -0,0 +1,159 @@
+package gorms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/req"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CursorInfo 游标分页参数，第一页不传 cursor，之后传上一页返回的 nextCursor
+type CursorInfo struct {
+	Cursor string `json:"cursor" form:"cursor"`
+	Limit  int    `json:"limit" form:"limit"`
+}
+
+// CursorPage 游标分页结果
+type CursorPage[T any] struct {
+	List       []*T   `json:"list"`
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore"`
+}
+
+// Keyset 游标分页的排序字段，字段名可以是结构体字段名或列名
+// 字段组合不唯一时自动追加主键，Columns 为空时只按主键排序
+type Keyset struct {
+	Columns []string
+	Desc    bool
+}
+
+// PaginateKeyset 按 Keyset 排序做游标分页，用上一页最后一行的排序字段值作为条件，
+// 不需要 count 和 offset，适合数据量大的表和无限滚动列表，排序字段需要有索引
+//
+//	page, err := gorms.PaginateKeyset[Order](ctx, gorms.DB(ctx),
+//		gorms.Keyset{Columns: []string{"CreatedAt"}, Desc: true}, cursorInfo)
+func PaginateKeyset[T any](ctx context.Context, db *gorm.DB, keyset Keyset, c CursorInfo, scopes ...Scope) (*CursorPage[T], error) {
+	fields, err := keysetFields[T](db, keyset.Columns)
+	if err != nil {
+		return nil, err
+	}
+	_, limit := NormalizePage(req.PageInfo{PageSize: c.Limit})
+	query := db.WithContext(ctx).Model(new(T)).Scopes(scopes...)
+	if c.Cursor != "" {
+		values, err := decodeCursor(fields, c.Cursor)
+		if err != nil {
+			logs.CtxWarn(ctx, "invalid cursor", "cursor", c.Cursor, "error", err)
+			return nil, errs.ErrParam
+		}
+		query = query.Where(keysetCondition(fields, values, keyset.Desc))
+	}
+	for _, f := range fields {
+		query = query.Order(clause.OrderByColumn{Column: column(f), Desc: keyset.Desc})
+	}
+	list := make([]*T, 0, limit+1)
+	if err := query.Limit(limit + 1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	page := &CursorPage[T]{List: list}
+	if len(list) > limit {
+		page.List = list[:limit]
+		page.HasMore = true
+		page.NextCursor, err = encodeCursor(ctx, fields, page.List[limit-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func keysetFields[T any](db *gorm.DB, columns []string) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, fmt.Errorf("gorms: %s has no primary key for keyset pagination", stmt.Schema.Name)
+	}
+	fields := make([]*schema.Field, 0, len(columns)+1)
+	hasPK := false
+	for _, name := range columns {
+		f := stmt.Schema.LookUpField(name)
+		if f == nil || f.DBName == "" {
+			return nil, fmt.Errorf("gorms: unknown keyset column %q of %s", name, stmt.Schema.Name)
+		}
+		hasPK = hasPK || f == pk
+		fields = append(fields, f)
+	}
+	if !hasPK {
+		fields = append(fields, pk)
+	}
+	return fields, nil
+}
+
+// keysetCondition 生成 (c1 > v1) OR (c1 = v1 AND c2 > v2) ...，不依赖行值比较语法
+func keysetCondition(fields []*schema.Field, values []any, desc bool) clause.Expression {
+	ors := make([]clause.Expression, 0, len(fields))
+	for i, f := range fields {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: column(fields[j]), Value: values[j]})
+		}
+		if desc {
+			ands = append(ands, clause.Lt{Column: column(f), Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column(f), Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+func column(f *schema.Field) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: f.DBName}
+}
+
+func encodeCursor(ctx context.Context, fields []*schema.Field, row any) (string, error) {
+	rv := reflect.ValueOf(row)
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i], _ = f.ValueOf(ctx, rv)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 按字段类型解析游标中的值，避免 int64 主键经过 float64 丢失精度
+func decodeCursor(fields []*schema.Field, cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	if len(raws) != len(fields) {
+		return nil, fmt.Errorf("cursor has %d values, want %d", len(raws), len(fields))
+	}
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		v := reflect.New(f.FieldType)
+		if err := json.Unmarshal(raws[i], v.Interface()); err != nil {
+			return nil, err
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, nil
+}
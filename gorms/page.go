@@ -0,0 +1,74 @@
+package gorms
+
+import (
+	"context"
+
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/res"
+	"gorm.io/gorm"
+)
+
+var (
+	// DefaultPageSize 未传 pageSize 时的每页条数
+	DefaultPageSize = 20
+	// MaxPageSize 每页条数上限，防止一次查询过多数据
+	MaxPageSize = 100
+)
+
+// Scope gorm 的查询条件，通过 db.Scopes 组合使用
+type Scope = func(*gorm.DB) *gorm.DB
+
+// NormalizePage 返回修正后的页码和每页条数，页码从 1 开始，每页条数不超过 MaxPageSize
+func NormalizePage(p req.PageInfo) (page, size int) {
+	page, size = p.Page, p.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = DefaultPageSize
+	}
+	if size > MaxPageSize {
+		size = MaxPageSize
+	}
+	return page, size
+}
+
+// PageScope 按页码设置 offset 和 limit
+func PageScope(p req.PageInfo) Scope {
+	page, size := NormalizePage(p)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset((page - 1) * size).Limit(size)
+	}
+}
+
+// Paginate 在 db 上执行分页查询，返回总数和当前页的 []*T
+//
+//	page, err := gorms.Paginate[User](ctx, gorms.DB(ctx), pageInfo, gorms.Where("status = ?", 1))
+func Paginate[T any](ctx context.Context, db *gorm.DB, p req.PageInfo, scopes ...Scope) (*res.Page, error) {
+	page, size := NormalizePage(p)
+	query := db.WithContext(ctx).Model(new(T)).Scopes(scopes...)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*T, 0)
+	offset := (page - 1) * size
+	if int64(offset) < total {
+		if err := query.Session(&gorm.Session{}).Offset(offset).Limit(size).Find(&list).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &res.Page{
+		Total:       total,
+		List:        list,
+		PageSize:    int64(size),
+		CurrentPage: int64(page),
+	}, nil
+}
+
+// Where 把查询条件包装为 Scope
+func Where(query any, args ...any) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}
+}
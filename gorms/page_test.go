@@ -0,0 +1,48 @@
+package gorms
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/req"
+)
+
+func TestNormalizePage(t *testing.T) {
+	cases := []struct {
+		in         req.PageInfo
+		page, size int
+	}{
+		{req.PageInfo{}, 1, DefaultPageSize},
+		{req.PageInfo{Page: -1, PageSize: 5}, 1, 5},
+		{req.PageInfo{Page: 3, PageSize: 1000}, 3, MaxPageSize},
+	}
+	for _, c := range cases {
+		page, size := NormalizePage(c.in)
+		if page != c.page || size != c.size {
+			t.Fatalf("NormalizePage(%+v) = %d, %d, want %d, %d", c.in, page, size, c.page, c.size)
+		}
+	}
+}
+
+func TestQueryParserWhitelist(t *testing.T) {
+	parser := &QueryParser{
+		Sorts:   map[string]string{"createdAt": "created_at"},
+		Filters: map[string]string{"status": "status"},
+	}
+	valid := url.Values{"sort": {"-createdAt"}, "status[in]": {"1,2"}, "page": {"2"}}
+	if _, err := parser.Parse(valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := []url.Values{
+		{"sort": {"password"}},
+		{"password[like]": {"a"}},
+		{"status[regex]": {"a"}},
+	}
+	for _, values := range invalid {
+		if _, err := parser.Parse(values); !errors.Is(err, errs.ErrParam) {
+			t.Fatalf("Parse(%v) error = %v, want ErrParam", values, err)
+		}
+	}
+}
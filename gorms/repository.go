@@ -0,0 +1,184 @@
+package gorms
+
+import (
+	"context"
+
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/res"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 通用仓储，提供 T 的增删改查和分页，自动加入 context 中的事务
+// 业务仓储嵌入它后只需要补充自己的查询方法
+//
+//	type UserRepo struct {
+//		*gorms.Repository[User]
+//	}
+//
+//	func NewUserRepo() *UserRepo {
+//		return &UserRepo{Repository: gorms.NewRepository[User](nil)}
+//	}
+type Repository[T any] struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建仓储，db 为 nil 时使用默认数据库，命名数据源传入 database.GetDatasource(name)
+func NewRepository[T any](db *gorm.DB) *Repository[T] {
+	return &Repository[T]{db: db}
+}
+
+// DB 返回绑定 ctx 的连接，在事务中时返回事务，已设置 Model
+// 没有可用的数据库时返回 ErrNoDB
+func (r *Repository[T]) DB(ctx context.Context) (*gorm.DB, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.Model(new(T)), nil
+}
+
+func (r *Repository[T]) conn(ctx context.Context) (*gorm.DB, error) {
+	db := Use(ctx, r.db)
+	if db == nil {
+		return nil, ErrNoDB
+	}
+	return db, nil
+}
+
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Create(entity).Error
+}
+
+// CreateBatch 批量插入，batchSize 不大于 0 时一次插入全部
+func (r *Repository[T]) CreateBatch(ctx context.Context, entities []*T, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	db, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		return db.Create(entities).Error
+	}
+	return db.CreateInBatches(entities, batchSize).Error
+}
+
+// Save 保存所有字段，主键为零值时插入
+func (r *Repository[T]) Save(ctx context.Context, entity *T) error {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Save(entity).Error
+}
+
+// Updates 按主键更新指定字段，values 为 map 或结构体，结构体中的零值字段不会更新
+func (r *Repository[T]) Updates(ctx context.Context, id any, values any) (int64, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Model(new(T)).Where(primaryKey(id)).Updates(values)
+	return result.RowsAffected, result.Error
+}
+
+// Delete 按主键删除，模型有 gorm.DeletedAt 字段时为软删除
+func (r *Repository[T]) Delete(ctx context.Context, id any) (int64, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Where(primaryKey(id)).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
+// FindByID 按主键查询，不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) FindByID(ctx context.Context, id any) (*T, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entity := new(T)
+	if err := db.Where(primaryKey(id)).First(entity).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// First 查询第一条记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) First(ctx context.Context, scopes ...Scope) (*T, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entity := new(T)
+	if err := db.Scopes(scopes...).First(entity).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *Repository[T]) Find(ctx context.Context, scopes ...Scope) ([]*T, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*T, 0)
+	if err := db.Scopes(scopes...).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *Repository[T]) Count(ctx context.Context, scopes ...Scope) (int64, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = db.Model(new(T)).Scopes(scopes...).Count(&total).Error
+	return total, err
+}
+
+func (r *Repository[T]) Exists(ctx context.Context, scopes ...Scope) (bool, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	list := make([]*T, 0, 1)
+	if err := db.Scopes(scopes...).Limit(1).Find(&list).Error; err != nil {
+		return false, err
+	}
+	return len(list) > 0, nil
+}
+
+// Paginate 分页查询，页码和每页条数按 NormalizePage 修正
+func (r *Repository[T]) Paginate(ctx context.Context, p req.PageInfo, scopes ...Scope) (*res.Page, error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Paginate[T](ctx, db, p, scopes...)
+}
+
+// PaginateKeyset 游标分页，见 PaginateKeyset
+func (r *Repository[T]) PaginateKeyset(ctx context.Context, keyset Keyset, c CursorInfo, scopes ...Scope) (*CursorPage[T], error) {
+	db, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return PaginateKeyset[T](ctx, db, keyset, c, scopes...)
+}
+
+// primaryKey 按主键匹配的条件，id 始终作为参数绑定
+// 不能直接把 id 传给 Where、First 等方法，gorm 会把字符串当作 SQL 条件拼接
+// id 为切片时生成 IN 条件
+func primaryKey(id any) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
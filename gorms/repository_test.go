@@ -0,0 +1,240 @@
+package gorms
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/req"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type repoUser struct {
+	ID        int64
+	Name      string
+	Status    int
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+// sqlRecorder 记录执行的 SQL，DryRun 模式下也会调用 Trace
+type sqlRecorder struct {
+	logger.Interface
+	mu   sync.Mutex
+	sqls []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sqls = append(r.sqls, sql)
+}
+
+func (r *sqlRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sqls) == 0 {
+		return ""
+	}
+	return r.sqls[len(r.sqls)-1]
+}
+
+// dryRunDB 只生成 SQL 不执行
+func dryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true, Logger: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, rec
+}
+
+// recordDB 执行 SQL 的 sqlite 数据库，同时记录 SQL
+func recordDB(t *testing.T, models ...any) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{Interface: logger.Discard}
+	db := openTestDB(t, models...)
+	return db.Session(&gorm.Session{Logger: rec}), rec
+}
+
+func TestRepositoryPrimaryKeyIsBound(t *testing.T) {
+	db, rec := dryRunDB(t)
+	repo := NewRepository[repoUser](db)
+	ctx := context.Background()
+	cases := []struct {
+		run  func()
+		want string
+	}{
+		{
+			run:  func() { _, _ = repo.FindByID(ctx, "1 OR 1=1") },
+			want: "SELECT * FROM `repo_users` WHERE `repo_users`.`id` = \"1 OR 1=1\" AND `repo_users`.`deleted_at` IS NULL ORDER BY `repo_users`.`id` LIMIT 1",
+		},
+		{
+			run:  func() { _, _ = repo.Updates(ctx, "1=1", map[string]any{"name": "x"}) },
+			want: "UPDATE `repo_users` SET `name`=\"x\" WHERE `repo_users`.`id` = \"1=1\" AND `repo_users`.`deleted_at` IS NULL",
+		},
+		{
+			run:  func() { _, _ = repo.Delete(ctx, "1=1") },
+			want: "WHERE `repo_users`.`id` = \"1=1\" AND `repo_users`.`deleted_at` IS NULL",
+		},
+		{
+			run:  func() { _, _ = repo.Delete(ctx, []int64{1, 2}) },
+			want: "WHERE `repo_users`.`id` IN (1,2) AND `repo_users`.`deleted_at` IS NULL",
+		},
+	}
+	for _, c := range cases {
+		c.run()
+		if sql := rec.last(); !strings.HasSuffix(sql, c.want) {
+			t.Fatalf("id should be bound as a value\n got: %s\nwant: %s", sql, c.want)
+		}
+	}
+}
+
+func TestRepositoryNoDB(t *testing.T) {
+	repo := NewRepository[repoUser](nil)
+	if _, err := repo.DB(context.Background()); !errors.Is(err, ErrNoDB) {
+		t.Fatalf("want ErrNoDB, got %v", err)
+	}
+	if _, err := repo.FindByID(context.Background(), 1); !errors.Is(err, ErrNoDB) {
+		t.Fatalf("want ErrNoDB, got %v", err)
+	}
+}
+
+func seedUsers(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for i, name := range []string{"a", "b", "b", "c", "d"} {
+		if err := db.Create(&repoUser{Name: name, Status: i % 2}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db, rec := recordDB(t, &repoUser{})
+	seedUsers(t, db)
+	repo := NewRepository[repoUser](db)
+	page, err := repo.Paginate(context.Background(), req.PageInfo{Page: 2, PageSize: 2}, Where("status = ?", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := page.List.([]*repoUser)
+	if page.Total != 3 || page.CurrentPage != 2 || page.PageSize != 2 || len(list) != 1 || list[0].Name != "d" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	want := "SELECT * FROM `repo_users` WHERE status = 0 AND `repo_users`.`deleted_at` IS NULL LIMIT 2 OFFSET 2"
+	if sql := rec.last(); sql != want {
+		t.Fatalf("page sql\n got: %s\nwant: %s", sql, want)
+	}
+
+	// 超出总数的页不再查询列表
+	n := len(rec.sqls)
+	page, err = repo.Paginate(context.Background(), req.PageInfo{Page: 10, PageSize: 2})
+	if err != nil || page.Total != 5 || len(page.List.([]*repoUser)) != 0 || len(rec.sqls) != n+1 {
+		t.Fatalf("out of range page %+v %v", page, err)
+	}
+}
+
+func TestPaginateKeyset(t *testing.T) {
+	db, rec := recordDB(t, &repoUser{})
+	seedUsers(t, db)
+	repo := NewRepository[repoUser](db)
+	ctx := context.Background()
+	keyset := Keyset{Columns: []string{"Name"}}
+
+	var names []string
+	cursor := CursorInfo{Limit: 2}
+	for {
+		page, err := repo.PaginateKeyset(ctx, keyset, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range page.List {
+			names = append(names, u.Name)
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor.Cursor = page.NextCursor
+	}
+	if strings.Join(names, "") != "abbcd" {
+		t.Fatalf("names = %v", names)
+	}
+	// 最后一页从 (name, id) = ("c", 4) 之后开始，相同 name 按主键区分
+	want := "SELECT * FROM `repo_users` WHERE (`repo_users`.`name` > \"c\" OR (`repo_users`.`name` = \"c\" AND `repo_users`.`id` > 4)) AND `repo_users`.`deleted_at` IS NULL ORDER BY `repo_users`.`name`,`repo_users`.`id` LIMIT 3"
+	if sql := rec.last(); sql != want {
+		t.Fatalf("keyset sql\n got: %s\nwant: %s", sql, want)
+	}
+
+	page, err := repo.PaginateKeyset(ctx, Keyset{Columns: []string{"Name"}, Desc: true}, CursorInfo{Limit: 2})
+	if err != nil || page.List[0].Name != "d" || !page.HasMore {
+		t.Fatalf("desc page %+v %v", page, err)
+	}
+	if _, err := repo.PaginateKeyset(ctx, keyset, CursorInfo{Cursor: "not-a-cursor"}); !errors.Is(err, errs.ErrParam) {
+		t.Fatalf("invalid cursor should be ErrParam, got %v", err)
+	}
+	if _, err := repo.PaginateKeyset(ctx, Keyset{Columns: []string{"Password"}}, CursorInfo{}); err == nil {
+		t.Fatal("unknown keyset column should fail")
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	db, _ := dryRunDB(t)
+	fields, err := keysetFields[repoUser](db, []string{"CreatedAt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := &repoUser{ID: 1<<60 + 1, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)}
+	cursor, err := encodeCursor(context.Background(), fields, row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := decodeCursor(fields, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// int64 主键不经过 float64，不丢失精度
+	if !values[0].(time.Time).Equal(row.CreatedAt) || values[1].(int64) != row.ID {
+		t.Fatalf("decoded %v", values)
+	}
+	if _, err := decodeCursor(fields[:1], cursor); err == nil {
+		t.Fatal("cursor with a different number of values should fail")
+	}
+}
+
+func TestQueryParserSQL(t *testing.T) {
+	db, _ := dryRunDB(t)
+	parser := &QueryParser{
+		Sorts:       map[string]string{"createdAt": "created_at", "name": "name"},
+		Filters:     map[string]string{"status": "status", "name": "name"},
+		DefaultSort: "-createdAt",
+	}
+	values := url.Values{"sort": {"-createdAt,name"}, "status[in]": {"1,2"}, "name[like]": {"a' OR '1'='1"}, "page": {"2"}}
+	scope, err := parser.Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&repoUser{}).Scopes(scope).Find(&[]*repoUser{})
+	})
+	want := "SELECT * FROM `repo_users` WHERE (`name` LIKE \"%a' OR '1'='1%\" AND `status` IN (\"1\",\"2\")) AND `repo_users`.`deleted_at` IS NULL ORDER BY `created_at` DESC,`name`"
+	if sql != want {
+		t.Fatalf("parser sql\n got: %s\nwant: %s", sql, want)
+	}
+	scope, _ = parser.Parse(url.Values{"status": {"1"}})
+	sql = db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&repoUser{}).Scopes(scope).Find(&[]*repoUser{})
+	})
+	want = "SELECT * FROM `repo_users` WHERE `status` = \"1\" AND `repo_users`.`deleted_at` IS NULL ORDER BY `created_at` DESC"
+	if sql != want {
+		t.Fatalf("default sort sql\n got: %s\nwant: %s", sql, want)
+	}
+}
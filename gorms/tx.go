@@ -0,0 +1,182 @@
+package gorms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/zhangc-zwl/thunder/database"
+	"gorm.io/gorm"
+)
+
+// ErrNoDB 没有设置默认数据库，也没有初始化 MySQL 或 Postgres
+var ErrNoDB = errors.New("gorms: no database configured")
+
+var (
+	defaultMu sync.RWMutex
+	defaultDB *gorm.DB
+)
+
+// SetDefault 设置 WithTx 和 DB 使用的默认数据库
+// 未设置时依次使用 database.GetMysqlDB 和 database.GetPostgresDB
+func SetDefault(db *gorm.DB) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultDB = db
+}
+
+func getDefault() *gorm.DB {
+	defaultMu.RLock()
+	db := defaultDB
+	defaultMu.RUnlock()
+	if db != nil {
+		return db
+	}
+	if m := database.GetMysqlDB(); m != nil {
+		return m.GormDB
+	}
+	if p := database.GetPostgresDB(); p != nil {
+		return p.GormDB
+	}
+	return nil
+}
+
+type txKey struct{}
+
+// txState 一层事务，嵌套事务对应一个 savepoint
+type txState struct {
+	root          *gorm.DB
+	db            *gorm.DB
+	afterCommit   []func(ctx context.Context)
+	afterRollback []func(ctx context.Context)
+}
+
+// DB 返回当前 context 中的事务，不在事务中时返回默认数据库，都已绑定 ctx
+// 仓储代码统一通过它获取连接，即可自动加入调用方开启的事务
+//
+//	func (r *UserRepo) Create(ctx context.Context, u *User) error {
+//		return gorms.DB(ctx).Create(u).Error
+//	}
+func DB(ctx context.Context) *gorm.DB {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.db.WithContext(ctx)
+	}
+	db := getDefault()
+	if db == nil {
+		return nil
+	}
+	return db.WithContext(ctx)
+}
+
+// Use 返回 db 上绑定 ctx 的连接，context 中有同一数据库的事务时返回该事务
+// db 为 nil 时等同于 DB(ctx)
+// 是否同一数据库按 *gorm.DB 指针判断，必须传入开启事务时的同一个实例，
+// db.WithContext(ctx)、db.Session(...) 等返回的是新实例，不会加入已有事务，而是作为独立的连接执行
+func Use(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if db == nil {
+		return DB(ctx)
+	}
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.root == db {
+		return state.db.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// InTx 当前 context 是否在事务中
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// WithTx 在默认数据库上执行事务，fn 返回错误或 panic 时回滚
+// 已经在事务中时作为嵌套事务执行，使用 savepoint，只回滚 fn 中的修改
+//
+//	err := gorms.WithTx(ctx, func(ctx context.Context) error {
+//		if err := orderRepo.Create(ctx, order); err != nil {
+//			return err
+//		}
+//		gorms.AfterCommit(ctx, func(ctx context.Context) {
+//			event.Trigger("order.created", order)
+//		})
+//		return stockRepo.Decrease(ctx, order.Items)
+//	})
+func WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return WithTxDB(ctx, state.root, fn, opts...)
+	}
+	db := getDefault()
+	if db == nil {
+		return ErrNoDB
+	}
+	return WithTxDB(ctx, db, fn, opts...)
+}
+
+// WithTxDB 在指定数据库上执行事务，用于命名数据源
+// context 中已有同一数据库的事务时作为嵌套事务执行，不同数据库之间的事务相互独立
+// 与 Use 一样按 *gorm.DB 指针判断是否同一数据库，传入 db.WithContext(ctx) 会开启一个独立的事务
+func WithTxDB(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	parent, _ := ctx.Value(txKey{}).(*txState)
+	if parent != nil && parent.root != db {
+		parent = nil
+	}
+	state := &txState{root: db}
+	conn := db
+	if parent != nil {
+		conn = parent.db
+	}
+	finished := false
+	defer func() {
+		// fn panic 时 gorm 已经回滚，这里只执行回滚钩子
+		if !finished {
+			state.rollback(ctx)
+		}
+	}()
+	err := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	}, opts...)
+	finished = true
+	if err != nil {
+		state.rollback(ctx)
+		return err
+	}
+	if parent != nil {
+		// savepoint 提交后仍依赖外层事务，钩子交给外层处理
+		parent.afterCommit = append(parent.afterCommit, state.afterCommit...)
+		parent.afterRollback = append(parent.afterRollback, state.afterRollback...)
+		return nil
+	}
+	for _, hook := range state.afterCommit {
+		hook(ctx)
+	}
+	return nil
+}
+
+// AfterCommit 注册最外层事务提交后执行的钩子，适合发送消息、清除缓存等不可回滚的操作
+// 不在事务中时立即执行
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	state.afterCommit = append(state.afterCommit, fn)
+}
+
+// AfterRollback 注册事务回滚后执行的钩子，嵌套事务回滚到 savepoint 时也会执行
+// 不在事务中时不会执行
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterRollback = append(state.afterRollback, fn)
+	}
+}
+
+func (s *txState) rollback(ctx context.Context) {
+	hooks := s.afterRollback
+	s.afterCommit = nil
+	s.afterRollback = nil
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+}
@@ -0,0 +1,198 @@
+package gorms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type txItem struct {
+	ID   uint
+	Name string
+}
+
+// openTestDB 打开临时目录中的 sqlite 数据库
+func openTestDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := db.DB(); err == nil {
+			_ = conn.Close()
+		}
+	})
+	return db
+}
+
+func itemNames(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	if err := db.Model(&txItem{}).Order("id").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestWithTxNoDB(t *testing.T) {
+	err := WithTx(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrNoDB) {
+		t.Fatalf("expected ErrNoDB, got %v", err)
+	}
+}
+
+func TestHooksOutsideTx(t *testing.T) {
+	ctx := context.Background()
+	committed, rolledBack := false, false
+	AfterCommit(ctx, func(context.Context) { committed = true })
+	AfterRollback(ctx, func(context.Context) { rolledBack = true })
+	if !committed || rolledBack {
+		t.Fatalf("committed=%v rolledBack=%v", committed, rolledBack)
+	}
+	if InTx(ctx) {
+		t.Fatal("background context should not be in a transaction")
+	}
+}
+
+func TestNestedTxSavepoint(t *testing.T) {
+	db := openTestDB(t, &txItem{})
+	ctx := context.Background()
+	var hooks []string
+	errNested := errors.New("nested failed")
+	err := WithTxDB(ctx, db, func(ctx context.Context) error {
+		if err := Use(ctx, db).Create(&txItem{Name: "a"}).Error; err != nil {
+			return err
+		}
+		// 嵌套事务失败只回滚到 savepoint
+		err := WithTxDB(ctx, db, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "nested commit") })
+			AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "nested rollback") })
+			if err := Use(ctx, db).Create(&txItem{Name: "b"}).Error; err != nil {
+				return err
+			}
+			return errNested
+		})
+		if !errors.Is(err, errNested) {
+			t.Fatalf("nested error = %v", err)
+		}
+		return Use(ctx, db).Create(&txItem{Name: "c"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := itemNames(t, db); !reflect.DeepEqual(names, []string{"a", "c"}) {
+		t.Fatalf("names = %v", names)
+	}
+	if !reflect.DeepEqual(hooks, []string{"nested rollback"}) {
+		t.Fatalf("hooks = %v", hooks)
+	}
+}
+
+func TestAfterCommitOrder(t *testing.T) {
+	db := openTestDB(t, &txItem{})
+	ctx := context.Background()
+	var hooks []string
+	err := WithTxDB(ctx, db, func(ctx context.Context) error {
+		AfterCommit(ctx, func(ctx context.Context) {
+			// 钩子在提交之后执行，已不在事务中
+			if InTx(ctx) || len(itemNames(t, db)) != 1 {
+				t.Error("hook should run after commit")
+			}
+			hooks = append(hooks, "outer 1")
+		})
+		err := WithTxDB(ctx, db, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "nested") })
+			return Use(ctx, db).Create(&txItem{Name: "a"}).Error
+		})
+		if err != nil {
+			return err
+		}
+		if len(hooks) != 0 {
+			t.Error("savepoint commit should not run hooks")
+		}
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "outer 2") })
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hooks, []string{"outer 1", "nested", "outer 2"}) {
+		t.Fatalf("hooks = %v", hooks)
+	}
+}
+
+func TestOuterRollbackHooks(t *testing.T) {
+	db := openTestDB(t, &txItem{})
+	ctx := context.Background()
+	var hooks []string
+	errOuter := errors.New("outer failed")
+	err := WithTxDB(ctx, db, func(ctx context.Context) error {
+		_ = WithTxDB(ctx, db, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "nested commit") })
+			AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "nested rollback") })
+			return Use(ctx, db).Create(&txItem{Name: "a"}).Error
+		})
+		AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "outer rollback") })
+		return errOuter
+	})
+	if !errors.Is(err, errOuter) {
+		t.Fatalf("err = %v", err)
+	}
+	// 已提交的 savepoint 随外层事务一起回滚
+	if names := itemNames(t, db); len(names) != 0 {
+		t.Fatalf("names = %v", names)
+	}
+	if !reflect.DeepEqual(hooks, []string{"nested rollback", "outer rollback"}) {
+		t.Fatalf("hooks = %v", hooks)
+	}
+
+	// fn panic 时同样执行回滚钩子，panic 继续向上抛出
+	hooks = nil
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic should be propagated")
+			}
+		}()
+		_ = WithTxDB(ctx, db, func(ctx context.Context) error {
+			AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "panic rollback") })
+			panic("boom")
+		})
+	}()
+	if !reflect.DeepEqual(hooks, []string{"panic rollback"}) {
+		t.Fatalf("hooks = %v", hooks)
+	}
+}
+
+func TestUseRootIdentity(t *testing.T) {
+	db := openTestDB(t, &txItem{})
+	other := openTestDB(t, &txItem{})
+	ctx := context.Background()
+	err := WithTxDB(ctx, db, func(ctx context.Context) error {
+		if _, ok := Use(ctx, db).Statement.ConnPool.(*sql.Tx); !ok {
+			t.Error("Use with the same *gorm.DB should join the transaction")
+		}
+		if _, ok := Use(ctx, other).Statement.ConnPool.(*sql.Tx); ok {
+			t.Error("another database should not join the transaction")
+		}
+		// 按指针判断，WithContext 返回的新实例不会加入事务
+		if _, ok := Use(ctx, db.WithContext(ctx)).Statement.ConnPool.(*sql.Tx); ok {
+			t.Error("a derived *gorm.DB should not join the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
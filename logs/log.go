@@ -15,8 +15,8 @@ import (
 	"github.com/zhangc-zwl/thunder/config"
 )
 
-// 定义一个私有的全局 logger 实例
-var defaultLogger *slog.Logger
+// 定义一个私有的全局 logger 实例，未调用 Init 前使用 slog 的默认 logger
+var defaultLogger = slog.Default()
 
 // 为了在 context 中传递 logger，我们定义一个私有的 key 类型
 type loggerKey struct{}
@@ -0,0 +1,74 @@
+package midd
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+const (
+	// RequestIdHeader 请求 ID 的请求头/响应头
+	RequestIdHeader = "X-Request-ID"
+	// RequestIdKey 请求 ID 在 gin.Context 中的 key
+	RequestIdKey = "requestId"
+)
+
+// AccessLog 访问日志中间件
+// 1. 从请求头中读取 X-Request-ID，没有则生成一个，并回写到响应头
+// 2. 将带有 request_id 的 logger 存入请求的 context，后续通过 logs.CtxInfo(c.Request.Context(), ...) 输出的日志都会带上 request_id
+// 3. 请求结束后通过 logs 输出一条结构化的访问日志
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > 64 {
+			requestId = uuid.NewString()
+		}
+		c.Set(RequestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+		ctx := logs.WithContext(c.Request.Context(), "request_id", requestId)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		route := c.FullPath()
+		if route == "" {
+			// 未匹配到路由时使用原始路径
+			route = c.Request.URL.Path
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userId, ok := c.Get("userId"); ok {
+			attrs = append(attrs, slog.Any("userId", userId))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		// 使用原始的 ctx，保证 logger 中带有 request_id
+		logs.FromContext(ctx).LogAttrs(ctx, level, "access", attrs...)
+	}
+}
+
+// GetRequestId 获取当前请求的请求 ID
+func GetRequestId(c *gin.Context) string {
+	return c.GetString(RequestIdKey)
+}
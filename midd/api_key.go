@@ -0,0 +1,156 @@
+package midd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/gorms"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/tools/crypro"
+	"github.com/zhangc-zwl/thunder/types"
+	"gorm.io/gorm"
+)
+
+// ApiKeyPrincipal API key 对应的调用方
+type ApiKeyPrincipal struct {
+	Name      string
+	Principal string
+	Scopes    []string
+}
+
+// ApiKeyStore API key 存储，只按 key 的 sha256 摘要查找，找不到时返回 nil, nil
+type ApiKeyStore interface {
+	Find(ctx context.Context, hash string) (*ApiKeyPrincipal, error)
+}
+
+var (
+	apiKeyStore   ApiKeyStore
+	apiKeyStoreMu sync.RWMutex
+)
+
+// SetApiKeyStore 设置全局的 API key 存储，不设置时使用配置文件中的 auth.apiKey.keys
+func SetApiKeyStore(store ApiKeyStore) {
+	apiKeyStoreMu.Lock()
+	defer apiKeyStoreMu.Unlock()
+	apiKeyStore = store
+}
+
+// GenerateApiKey 生成一个新的 API key，key 只在创建时展示给调用方，服务端只保存 hash
+func GenerateApiKey() (key string, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = "tk_" + hex.EncodeToString(b)
+	return key, crypro.Sha256(key), nil
+}
+
+// StaticApiKeyStore 基于配置文件的 API key 存储
+type StaticApiKeyStore map[string]*ApiKeyPrincipal
+
+// NewStaticApiKeyStore 从配置中加载 API key
+func NewStaticApiKeyStore(conf *config.ApiKey) StaticApiKeyStore {
+	store := make(StaticApiKeyStore)
+	if conf == nil {
+		return store
+	}
+	for _, item := range conf.Keys {
+		store[item.GetHash()] = &ApiKeyPrincipal{
+			Name:      item.GetName(),
+			Principal: item.GetPrincipal(),
+			Scopes:    item.Scopes,
+		}
+	}
+	return store
+}
+
+func (s StaticApiKeyStore) Find(_ context.Context, hash string) (*ApiKeyPrincipal, error) {
+	return s[hash], nil
+}
+
+// ApiKeyModel API key 表结构
+type ApiKeyModel struct {
+	ID        int64             `gorm:"primaryKey"`
+	Name      string            `gorm:"size:64"`
+	KeyHash   string            `gorm:"size:64;uniqueIndex"`
+	Principal string            `gorm:"size:64"`
+	Scopes    types.ArrayString `gorm:"size:1024"`
+	ExpiresAt *time.Time
+	Revoked   bool
+	CreatedAt time.Time
+}
+
+func (ApiKeyModel) TableName() string {
+	return "api_keys"
+}
+
+// GormApiKeyStore 基于数据库的 API key 存储
+type GormApiKeyStore struct {
+	db *gorm.DB
+}
+
+func NewGormApiKeyStore(db *gorm.DB) *GormApiKeyStore {
+	return &GormApiKeyStore{db: db}
+}
+
+func (s *GormApiKeyStore) Find(ctx context.Context, hash string) (*ApiKeyPrincipal, error) {
+	var m ApiKeyModel
+	err := s.db.WithContext(ctx).Where("key_hash = ? AND revoked = ?", hash, false).First(&m).Error
+	if err != nil {
+		if gorms.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if m.ExpiresAt != nil && m.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return &ApiKeyPrincipal{
+		Name:      m.Name,
+		Principal: m.Principal,
+		Scopes:    m.Scopes,
+	}, nil
+}
+
+// authApiKey 使用 API key 认证，返回 false 表示请求没有携带 API key
+// 携带了但校验失败时直接拒绝请求
+func authApiKey(c *gin.Context, conf *config.ApiKey, paths pathMatcher, static ApiKeyStore) bool {
+	key := c.GetHeader(conf.GetHeader())
+	if key == "" {
+		return false
+	}
+	if !paths.match(c.Request.URL.Path, true) {
+		reject(c, "API key is not allowed for this path", nil)
+		return true
+	}
+	apiKeyStoreMu.RLock()
+	store := apiKeyStore
+	apiKeyStoreMu.RUnlock()
+	if store == nil {
+		store = static
+	}
+	principal, err := store.Find(c.Request.Context(), crypro.Sha256(key))
+	if err != nil {
+		logs.CtxError(c.Request.Context(), "find api key err", "error", err)
+		reject(c, "Invalid API key", nil)
+		return true
+	}
+	if principal == nil {
+		reject(c, "Invalid API key", nil)
+		return true
+	}
+	c.Set("userId", principal.Principal)
+	c.Set("authType", "apiKey")
+	// scopes 作为权限交给授权中间件校验
+	c.Set("permissions", principal.Scopes)
+	ctx := req.WithUserId(c.Request.Context(), principal.Principal)
+	c.Request = c.Request.WithContext(logs.WithContext(ctx, "userId", principal.Principal, "apiKey", principal.Name))
+	c.Next()
+	return true
+}
@@ -0,0 +1,131 @@
+package midd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/req"
+	"github.com/zhangc-zwl/thunder/tools/crypro"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestGenerateApiKey(t *testing.T) {
+	key, hash, err := GenerateApiKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "tk_") || len(key) != 3+48 {
+		t.Fatalf("unexpected key %q", key)
+	}
+	if hash != crypro.Sha256(key) || strings.Contains(hash, key) {
+		t.Fatal("only the sha256 hash of the key should be stored")
+	}
+	other, _, _ := GenerateApiKey()
+	if other == key {
+		t.Fatal("keys should be random")
+	}
+}
+
+type apiKeyResult struct {
+	code        int
+	userId      string
+	ctxUserId   string
+	authType    string
+	permissions []string
+}
+
+func serveApiKey(conf *config.Auth, header string, value string, path string) apiKeyResult {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var result apiKeyResult
+	engine.Use(Auth(conf))
+	engine.GET("/*path", func(c *gin.Context) {
+		result.userId = c.GetString("userId")
+		result.ctxUserId = req.UserId(c.Request.Context())
+		result.authType = c.GetString("authType")
+		result.permissions = c.GetStringSlice("permissions")
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if value != "" {
+		r.Header.Set(header, value)
+	}
+	engine.ServeHTTP(w, r)
+	result.code = w.Code
+	return result
+}
+
+func TestApiKeyAuth(t *testing.T) {
+	key, hash, _ := GenerateApiKey()
+	conf := &config.Auth{
+		IsAuth: gptr.Of(true),
+		ApiKey: &config.ApiKey{
+			Enable: gptr.Of(true),
+			Paths:  []string{"/open/**", "/jobs/**"},
+			Only:   []string{"/jobs/**"},
+			Keys: []*config.ApiKeyItem{
+				{Name: gptr.Of("billing"), Hash: gptr.Of(hash), Principal: gptr.Of("svc-billing"), Scopes: []string{"order:read"}},
+			},
+		},
+	}
+	got := serveApiKey(conf, "X-API-Key", key, "/open/orders")
+	if got.code != http.StatusOK || got.userId != "svc-billing" || got.ctxUserId != "svc-billing" || got.authType != "apiKey" {
+		t.Fatalf("valid key: %+v", got)
+	}
+	if len(got.permissions) != 1 || got.permissions[0] != "order:read" {
+		t.Fatalf("scopes should become permissions, got %v", got.permissions)
+	}
+	if got := serveApiKey(conf, "X-API-Key", key+"x", "/open/orders"); got.code != http.StatusUnauthorized {
+		t.Fatalf("wrong key: %d", got.code)
+	}
+	if got := serveApiKey(conf, "X-API-Key", key, "/admin"); got.code != http.StatusUnauthorized {
+		t.Fatalf("path outside apiKey.paths: %d", got.code)
+	}
+	// only 路径不接受 JWT，没有 API key 时直接拒绝
+	if got := serveApiKey(conf, "Authorization", "Bearer x", "/jobs/sync"); got.code != http.StatusUnauthorized {
+		t.Fatalf("only path without api key: %d", got.code)
+	}
+
+	conf.ApiKey.Header = gptr.Of("X-Token")
+	if got := serveApiKey(conf, "X-Token", key, "/jobs/sync"); got.code != http.StatusOK || got.userId != "svc-billing" {
+		t.Fatalf("custom header: %+v", got)
+	}
+}
+
+func TestApiKeyStoreOverride(t *testing.T) {
+	key, hash, _ := GenerateApiKey()
+	var lookedUp string
+	SetApiKeyStore(apiKeyStoreFunc(func(_ context.Context, h string) (*ApiKeyPrincipal, error) {
+		lookedUp = h
+		if h == hash {
+			return &ApiKeyPrincipal{Name: "db", Principal: "42"}, nil
+		}
+		if h == crypro.Sha256("broken") {
+			return nil, errors.New("db down")
+		}
+		return nil, nil
+	}))
+	defer SetApiKeyStore(nil)
+	conf := &config.Auth{IsAuth: gptr.Of(true), ApiKey: &config.ApiKey{Enable: gptr.Of(true)}}
+	if got := serveApiKey(conf, "X-API-Key", key, "/any"); got.code != http.StatusOK || got.userId != "42" {
+		t.Fatalf("store key: %+v", got)
+	}
+	if lookedUp != hash {
+		t.Fatal("store should be queried by hash, not by the raw key")
+	}
+	if got := serveApiKey(conf, "X-API-Key", "broken", "/any"); got.code != http.StatusUnauthorized {
+		t.Fatalf("store error: %d", got.code)
+	}
+}
+
+type apiKeyStoreFunc func(ctx context.Context, hash string) (*ApiKeyPrincipal, error)
+
+func (f apiKeyStoreFunc) Find(ctx context.Context, hash string) (*ApiKeyPrincipal, error) {
+	return f(ctx, hash)
+}
@@ -1,31 +1,49 @@
 package midd
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
-	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/req"
 	"github.com/zhangc-zwl/thunder/tools/jwt"
 )
 
 func Auth(authConf *config.Auth) gin.HandlerFunc {
+	apiKeyConf := authConf.ApiKey
+	staticKeys := NewStaticApiKeyStore(apiKeyConf)
+	ignores := newPathMatcher(authConf.GetIgnores()...)
+	needLogins := newPathMatcher(authConf.NeedLogins...)
+	var apiKeyPaths, apiKeyOnly pathMatcher
+	if apiKeyConf != nil {
+		apiKeyPaths = newPathMatcher(apiKeyConf.Paths...)
+		apiKeyOnly = newPathMatcher(apiKeyConf.Only...)
+	}
 	return func(c *gin.Context) {
 		if authConf.IsAuth == nil || !*authConf.IsAuth {
 			return
 		}
-		for _, pattern := range authConf.GetIgnores() {
-			if isMatch(c.Request.URL.Path, pattern) {
-				c.Next()
+		if ignores.match(c.Request.URL.Path, false) {
+			c.Next()
+			return
+		}
+		// API key 认证，携带了 API key 的请求不再走 JWT
+		if apiKeyConf.GetEnable() {
+			if authApiKey(c, apiKeyConf, apiKeyPaths, staticKeys) {
+				return
+			}
+			if apiKeyOnly.match(c.Request.URL.Path, false) {
+				reject(c, "API key is missing", nil)
 				return
 			}
 		}
 		// 从请求头中获取 token
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			reject(c, "Authorization header is missing", authConf.NeedLogins)
+			reject(c, "Authorization header is missing", needLogins)
 			return
 		}
 		// 删除 "Bearer " 前缀，只保留 token 部分
@@ -34,29 +52,37 @@ func Auth(authConf *config.Auth) gin.HandlerFunc {
 		}
 		claims, err := jwt.ParseToken(tokenString)
 		if err != nil {
-			reject(c, "Invalid token", authConf.NeedLogins)
+			reject(c, "Invalid token", needLogins)
+			return
+		}
+		// refresh token 只能用于换取新的令牌，不能访问接口
+		if claims.TokenType == jwt.TokenTypeRefresh {
+			reject(c, "Invalid token", needLogins)
 			return
 		}
+		if err := jwt.CheckRevoked(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, jwt.ErrTokenRevoked) {
+				reject(c, "Token has been revoked", needLogins)
+				return
+			}
+			// 吊销状态查询失败时放行，避免 Redis 故障导致所有请求不可用
+			logs.CtxError(c.Request.Context(), "check token revoked err", "error", err)
+		}
 		c.Set("userId", claims.UserId)
+		c.Set("authType", "jwt")
+		c.Set("roles", claims.Roles)
+		c.Set("permissions", claims.Permissions)
+		c.Set("tenantId", claims.TenantId)
+		ctx := req.WithUserId(c.Request.Context(), claims.UserId)
+		c.Request = c.Request.WithContext(logs.WithContext(ctx, "userId", claims.UserId))
 		c.Next()
 	}
 }
-func reject(ctx *gin.Context, errMsg string, needLoginUrls []string) {
-	for _, v := range needLoginUrls {
-		if isMatch(ctx.Request.URL.Path, v) {
-			ctx.Next()
-			return
-		}
+func reject(ctx *gin.Context, errMsg string, needLogins pathMatcher) {
+	if needLogins.match(ctx.Request.URL.Path, false) {
+		ctx.Next()
+		return
 	}
 	ctx.JSON(http.StatusUnauthorized, gin.H{"error": errMsg})
 	ctx.Abort()
 }
-func isMatch(path string, pattern string) bool {
-	// 将 `**` 替换为 `.*`，以适应正则表达式中的任意字符匹配
-	pattern = strings.ReplaceAll(pattern, "**", ".*")
-	// 将 `pattern` 包装成正则表达式
-	regexPattern := fmt.Sprintf("^%s$", pattern)
-	fmt.Println(regexPattern)
-	matched, _ := regexp.MatchString(regexPattern, path)
-	return matched
-}
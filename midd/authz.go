@@ -0,0 +1,144 @@
+package midd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/res"
+)
+
+// subjectKey Subject 在 gin.Context 中的 key
+const subjectKey = "subject"
+
+var (
+	providers   []PolicyProvider
+	providersMu sync.RWMutex
+)
+
+// SetPolicyProviders 设置全局的权限来源，按顺序依次补全 Subject
+// 不设置时只使用 token 中携带的角色和权限
+func SetPolicyProviders(ps ...PolicyProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers = ps
+}
+
+// GetSubject 获取当前请求的访问主体，需要在 Auth 中间件之后使用
+// 同一个请求只会加载一次
+func GetSubject(c *gin.Context) (*Subject, error) {
+	if value, ok := c.Get(subjectKey); ok {
+		return value.(*Subject), nil
+	}
+	userId, ok := c.Get("userId")
+	if !ok {
+		return nil, errs.ErrUnauthorized
+	}
+	subject := &Subject{
+		UserId:      fmt.Sprint(userId),
+		Roles:       append([]string(nil), c.GetStringSlice("roles")...),
+		Permissions: append([]string(nil), c.GetStringSlice("permissions")...),
+	}
+	providersMu.RLock()
+	ps := providers
+	providersMu.RUnlock()
+	for _, p := range ps {
+		if err := p.Load(c.Request.Context(), subject); err != nil {
+			return nil, err
+		}
+	}
+	c.Set(subjectKey, subject)
+	return subject, nil
+}
+
+// RequireRoles 要求拥有任一角色，用于路由组
+//
+//	admin := engine.Group("/admin", midd.RequireRoles("admin"))
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authorize(c, roles, nil) {
+			c.Next()
+		}
+	}
+}
+
+// RequirePermissions 要求拥有全部权限，用于路由组或单个路由
+//
+//	api.POST("/articles", midd.RequirePermissions("article:write"), handler)
+func RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authorize(c, nil, permissions) {
+			c.Next()
+		}
+	}
+}
+
+// Authorize 按配置中的 method + path 规则校验角色和权限
+// 配置了 policyFile 且未调用过 SetPolicyProviders 时，会使用该策略文件作为权限来源
+func Authorize(conf *config.Authz) gin.HandlerFunc {
+	if path := conf.GetPolicyFile(); path != "" {
+		policy, err := NewFilePolicy(path)
+		if err != nil {
+			panic(err)
+		}
+		providersMu.Lock()
+		if len(providers) == 0 {
+			providers = []PolicyProvider{policy}
+		}
+		providersMu.Unlock()
+	}
+	rules := conf.Rules
+	paths := make([]pathMatcher, len(rules))
+	for i, rule := range rules {
+		paths[i] = newPathMatcher(rule.GetPath())
+	}
+	return func(c *gin.Context) {
+		if !conf.GetEnable() {
+			c.Next()
+			return
+		}
+		for i, rule := range rules {
+			if method := rule.GetMethod(); method != "" && !strings.EqualFold(method, c.Request.Method) {
+				continue
+			}
+			if !paths[i].match(c.Request.URL.Path, false) {
+				continue
+			}
+			if !authorize(c, rule.Roles, rule.Permissions) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// authorize 校验失败时写入响应并中断请求
+func authorize(c *gin.Context, roles []string, permissions []string) bool {
+	subject, err := GetSubject(c)
+	if err != nil {
+		if !errors.Is(err, errs.ErrUnauthorized) {
+			logs.CtxError(c.Request.Context(), "load subject policy err", "error", err)
+		}
+		res.Error(c, err)
+		c.Abort()
+		return false
+	}
+	if len(roles) > 0 && !subject.HasRole(roles...) {
+		res.Error(c, errs.ErrForbidden)
+		c.Abort()
+		return false
+	}
+	for _, permission := range permissions {
+		if !subject.HasPermission(permission) {
+			res.Error(c, errs.ErrForbidden)
+			c.Abort()
+			return false
+		}
+	}
+	return true
+}
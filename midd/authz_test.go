@@ -0,0 +1,125 @@
+package midd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestSubjectPermissions(t *testing.T) {
+	s := &Subject{Roles: []string{"editor"}, Permissions: []string{"article:*", "user:read"}}
+	if !s.HasRole("admin", "editor") || s.HasRole("admin") {
+		t.Fatal("HasRole should match any of the roles")
+	}
+	for permission, want := range map[string]bool{
+		"article:write": true,
+		"article:":      true,
+		"user:read":     true,
+		"user:write":    false,
+		"articles:read": false,
+	} {
+		if got := s.HasPermission(permission); got != want {
+			t.Errorf("HasPermission(%q) = %v, want %v", permission, got, want)
+		}
+	}
+	if !(&Subject{Permissions: []string{"*"}}).HasPermission("anything") {
+		t.Fatal("* should grant every permission")
+	}
+}
+
+func TestFilePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	content := "# roles\np, admin, article:*\np, editor, article:write\ng, 10001, admin\n\ng, 10002, editor\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewFilePolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := &Subject{UserId: "10002", Roles: []string{"viewer"}}
+	if err := policy.Load(context.Background(), subject); err != nil {
+		t.Fatal(err)
+	}
+	if !subject.HasRole("editor") || !subject.HasRole("viewer") || !subject.HasPermission("article:write") || subject.HasPermission("article:delete") {
+		t.Fatalf("unexpected subject %+v", subject)
+	}
+
+	if err := os.WriteFile(path, []byte("x, admin, article:*\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFilePolicy(path); err == nil {
+		t.Fatal("unknown line type should fail")
+	}
+}
+
+func serveAuthz(conf *config.Authz, userId string, roles []string, method, path string) int {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		if userId != "" {
+			c.Set("userId", userId)
+			c.Set("roles", roles)
+		}
+	}, Authorize(conf))
+	engine.Any("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w.Code
+}
+
+func TestAuthorizeRules(t *testing.T) {
+	conf := &config.Authz{Enable: gptr.Of(true), Rules: []*config.AuthzRule{
+		{Path: gptr.Of("/admin/**"), Roles: []string{"admin"}},
+		{Method: gptr.Of("POST"), Path: gptr.Of("/articles"), Permissions: []string{"article:write"}},
+	}}
+	cases := []struct {
+		userId string
+		roles  []string
+		method string
+		path   string
+		want   int
+	}{
+		{"", nil, http.MethodGet, "/admin/users", http.StatusUnauthorized},
+		{"1", []string{"editor"}, http.MethodGet, "/admin/users", http.StatusForbidden},
+		{"1", []string{"admin"}, http.MethodGet, "/admin/users", http.StatusOK},
+		{"1", nil, http.MethodGet, "/articles", http.StatusOK},
+		{"1", nil, http.MethodPost, "/articles", http.StatusForbidden},
+		{"", nil, http.MethodGet, "/public", http.StatusOK},
+	}
+	for _, c := range cases {
+		if got := serveAuthz(conf, c.userId, c.roles, c.method, c.path); got != c.want {
+			t.Errorf("%s %s as %q%v: got %d, want %d", c.method, c.path, c.userId, c.roles, got, c.want)
+		}
+	}
+
+	// provider 补全权限，返回包装后的 ErrUnauthorized 时仍然是 401
+	SetPolicyProviders(PolicyFunc(func(_ context.Context, s *Subject) error {
+		if s.UserId == "banned" {
+			return fmt.Errorf("load policy: %w", errs.ErrUnauthorized)
+		}
+		s.Permissions = append(s.Permissions, "article:write")
+		return nil
+	}))
+	defer SetPolicyProviders()
+	if got := serveAuthz(conf, "1", nil, http.MethodPost, "/articles"); got != http.StatusOK {
+		t.Fatalf("provider permissions: got %d", got)
+	}
+	if got := serveAuthz(conf, "banned", nil, http.MethodPost, "/articles"); got != http.StatusUnauthorized {
+		t.Fatalf("wrapped ErrUnauthorized: got %d", got)
+	}
+	SetPolicyProviders(PolicyFunc(func(context.Context, *Subject) error { return errors.New("db down") }))
+	if got := serveAuthz(conf, "1", nil, http.MethodPost, "/articles"); got != http.StatusInternalServerError {
+		t.Fatalf("provider error: got %d", got)
+	}
+}
@@ -2,16 +2,23 @@ package midd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/zhangc-zwl/thunder/cache"
 	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/database"
 	"github.com/zhangc-zwl/thunder/logs"
 	"github.com/zhangc-zwl/thunder/res"
 	"github.com/zhangc-zwl/thunder/tools/crypro"
-	"github.com/zhangc-zwl/thunder/tools/gptr"
 
 	"io"
 	"net/http"
@@ -30,60 +37,474 @@ func (w *CustomResponseWriter) Write(p []byte) (n int, err error) {
 	// 使用 gin 原有的 ResponseWriter 将数据写回客户端
 	return w.ResponseWriter.Write(p)
 }
+
+// bufferedWriter 缓存整个响应体，等 handler 执行完成后再统一写回客户端
+// 这样才能在写出响应前补充 ETag、Cache-Control 等响应头
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// cacheEntry 缓存在 Redis 中的响应
+type cacheEntry struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	ETag        string `json:"etag"`
+	Body        []byte `json:"body"`
+}
+
+// cacheRule 解析后的缓存规则
+type cacheRule struct {
+	paths   pathMatcher
+	methods []string
+	expire  int64
+	stale   int64
+	vary    []string
+	tags    []string
+}
+
+func (r *cacheRule) match(c *gin.Context) bool {
+	for _, method := range r.methods {
+		if method == c.Request.Method {
+			return r.paths.match(c.Request.URL.Path, false)
+		}
+	}
+	return false
+}
+
+const (
+	// cacheLockTTL 跨实例刷新锁的过期时间，防止持有锁的实例宕机后无法释放
+	cacheLockTTL = 10 * time.Second
+	// cacheLockWait 未拿到刷新锁时等待其他实例写入缓存的最长时间
+	cacheLockWait = 3 * time.Second
+)
+
+// flightResult 合并请求的执行结果
+type flightResult struct {
+	entry     *cacheEntry
+	cacheable bool
+	ttl       time.Duration
+	state     string
+}
+
+// Cache 响应缓存中间件
+// 缓存 GET 和 POST 请求的响应，缓存 key 由请求方法、路径、规范化后的查询参数、vary 请求头以及 POST 请求体计算
+// 命中时原样返回缓存的状态码和 Content-Type，并通过 X-Cache: HIT|MISS|STALE 标识是否命中
+// 缓存未命中时，同一实例内相同 key 的请求只会执行一次 handler，多个实例之间通过 Redis 锁保证只有一个实例回源
+// 配置了 staleWhileRevalidate 时，缓存过期后的一段时间内只有一个请求刷新缓存，其余请求直接返回旧数据
+// 需要按 userId 区分缓存时，应保证 Cache 挂载在 Auth 之后；Redis 尚未连接时请求直接交给 handler，不做缓存
 func Cache(cacheConfig *config.Cache) gin.HandlerFunc {
+	rules := buildCacheRules(cacheConfig)
+	flights := &flightGroup{calls: make(map[string]*flightCall)}
 	return func(c *gin.Context) {
-		//打印超时时间
-		start := time.Now()
-		for _, pattern := range cacheConfig.GetNeedCache() {
-			if isMatch(c.Request.URL.Path, pattern) {
-				//对数据进行缓存
-				if c.Request.Method == http.MethodPost {
-					body, err := io.ReadAll(c.Request.Body)
-					if err != nil {
-						c.AbortWithStatus(http.StatusInternalServerError)
-						return
-					}
-					c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-					cacheKey := fmt.Sprintf("CACHE:%s:%s", c.Request.RequestURI, crypro.Md5(body))
-					redisCache := cache.NewRedisCache()
-					writer := &CustomResponseWriter{body: bytes.NewBuffer([]byte{}), ResponseWriter: c.Writer}
-					c.Writer = writer
-					logs.Infof("cache -------start----- time: %s", time.Since(start))
-					if redisCache.Exist(cacheKey) {
-						logs.Infof("cache Exist time: %s", time.Since(start))
-						cacheData, err := redisCache.Get(cacheKey)
-						if err == nil {
-							c.Data(http.StatusOK, "application/json", []byte(cacheData))
-							c.Abort()
-							logs.Infof("cache time: %s", time.Since(start))
-							return
-						}
-						logs.Errorf("get cache err: %v", err)
-						c.Next()
-					} else {
-						c.Next()
-					}
-					if c.Writer.Status() == 200 {
-						responseBody := writer.body
-						var result res.Result
-						err := json.Unmarshal(responseBody.Bytes(), &result)
-						if err != nil {
-							logs.Errorf("cache json Unmarshal err: %v", err)
-						} else {
-							if result.Code == res.OK {
-								if cacheConfig.Expire == nil {
-									cacheConfig.Expire = gptr.Of(int64(5 * 60)) //默认5分钟
-								}
-								err := redisCache.Set(cacheKey, string(responseBody.Bytes()), cacheConfig.GetExpire())
-								if err != nil {
-									logs.Errorf("cache redisCache.Set err: %v", err)
-								}
-							}
-						}
-					}
+		var rule *cacheRule
+		for _, r := range rules {
+			if r.match(c) {
+				rule = r
+				break
+			}
+		}
+		// Redis 未配置或降级模式下尚未连接成功时不缓存
+		if rule == nil || database.GetRedis() == nil {
+			c.Next()
+			return
+		}
+		key, err := cacheKey(c, rule)
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx := c.Request.Context()
+		redisCache := cache.NewRedisCache()
+		if entry, ttl := loadCacheEntry(ctx, redisCache, key); entry != nil {
+			stale := time.Duration(rule.stale) * time.Second
+			// Redis 中的过期时间是 expire + stale，剩余时间大于 stale 说明仍然新鲜
+			if ttl > stale {
+				writeCacheEntry(c, rule, entry, ttl-stale, "HIT", true)
+				c.Abort()
+				return
+			}
+			// 已过期但在 stale 窗口内：拿到刷新锁的请求回源，其余请求返回旧数据
+			token, locked := tryCacheLock(ctx, redisCache, key)
+			if !locked {
+				writeCacheEntry(c, rule, entry, 0, "STALE", true)
+				c.Abort()
+				return
+			}
+			defer releaseCacheLock(ctx, redisCache, key, token)
+			fresh, cacheable := fetchAndStore(c, redisCache, rule, key)
+			writeCacheEntry(c, rule, fresh, time.Duration(rule.expire)*time.Second, "MISS", cacheable)
+			return
+		}
+
+		result, shared := flights.Do(key, func() *flightResult {
+			if token, locked := tryCacheLock(ctx, redisCache, key); !locked {
+				// 其他实例正在回源，等待它写入缓存
+				if entry, ttl := waitCacheEntry(ctx, redisCache, key); entry != nil {
+					ttl -= time.Duration(rule.stale) * time.Second
+					return &flightResult{entry: entry, cacheable: true, ttl: ttl, state: "HIT"}
 				}
+			} else {
+				defer releaseCacheLock(ctx, redisCache, key, token)
 			}
+			entry, cacheable := fetchAndStore(c, redisCache, rule, key)
+			return &flightResult{entry: entry, cacheable: cacheable, ttl: time.Duration(rule.expire) * time.Second, state: "MISS"}
+		})
+		if shared {
+			// 合并的请求只共享可缓存的响应，否则自己执行 handler
+			if result == nil || !result.cacheable {
+				c.Next()
+				return
+			}
+			writeCacheEntry(c, rule, result.entry, result.ttl, "HIT", true)
+			c.Abort()
+			return
+		}
+		if result == nil {
+			return
+		}
+		writeCacheEntry(c, rule, result.entry, result.ttl, result.state, result.cacheable)
+		if result.state == "HIT" {
+			c.Abort()
+		}
+	}
+}
+
+// fetchAndStore 执行后续 handler 并捕获响应，可缓存时写入 Redis
+func fetchAndStore(c *gin.Context, redisCache *cache.RedisCache, rule *cacheRule, key string) (*cacheEntry, bool) {
+	writer := &bufferedWriter{body: bytes.NewBuffer(nil), ResponseWriter: c.Writer}
+	c.Writer = writer
+	func() {
+		// handler panic 时也要还原 ResponseWriter，保证 Recovery 能把错误响应写回客户端
+		defer func() { c.Writer = writer.ResponseWriter }()
+		c.Next()
+	}()
+
+	entry := &cacheEntry{
+		Status:      writer.Status(),
+		ContentType: writer.Header().Get("Content-Type"),
+		Body:        writer.body.Bytes(),
+	}
+	entry.ETag = fmt.Sprintf(`"%s"`, crypro.Md5(entry.Body))
+	cacheable := isCacheable(entry)
+	if cacheable {
+		data, _ := json.Marshal(entry)
+		if err := redisCache.Set(key, string(data), rule.expire+rule.stale); err != nil {
+			logs.CtxError(c.Request.Context(), "set cache err", "key", key, "error", err)
 		}
+		tags := resolveCacheTags(c, rule.tags)
+		tags = append(tags, c.GetStringSlice(cacheTagsKey)...)
+		expire := time.Duration(rule.expire+rule.stale) * time.Second
+		if err := redisCache.AddTags(c.Request.Context(), key, expire, tags...); err != nil {
+			logs.CtxError(c.Request.Context(), "add cache tags err", "key", key, "error", err)
+		}
+	}
+	return entry, cacheable
+}
+
+// cacheTagsKey 路由上声明的缓存标签在 gin.Context 中的 key
+const cacheTagsKey = "cacheTags"
+
+// CacheTags 在路由上声明缓存标签，标签模板的写法与配置中的 tags 相同
+// 写操作完成后调用 cache.InvalidateTags 即可清除带有对应标签的缓存
+//
+//	api.GET("/articles/:id", midd.CacheTags("article:{id}"), handler)
+func CacheTags(tags ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resolved := append(c.GetStringSlice(cacheTagsKey), resolveCacheTags(c, tags)...)
+		c.Set(cacheTagsKey, resolved)
 		c.Next()
 	}
 }
+
+// resolveCacheTags 替换标签中的 {name} 占位符，取不到值的标签会被忽略
+func resolveCacheTags(c *gin.Context, tags []string) []string {
+	resolved := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		ok := true
+		var sb strings.Builder
+		for {
+			start := strings.IndexByte(tag, '{')
+			end := strings.IndexByte(tag, '}')
+			if start < 0 || end < start {
+				sb.WriteString(tag)
+				break
+			}
+			value := tagValue(c, tag[start+1:end])
+			if value == "" {
+				ok = false
+				break
+			}
+			sb.WriteString(tag[:start])
+			sb.WriteString(value)
+			tag = tag[end+1:]
+		}
+		if ok {
+			resolved = append(resolved, sb.String())
+		}
+	}
+	return resolved
+}
+
+func tagValue(c *gin.Context, name string) string {
+	if value := c.Param(name); value != "" {
+		return value
+	}
+	if value := c.Query(name); value != "" {
+		return value
+	}
+	if name == "userId" {
+		return varyValue(c, name)
+	}
+	return ""
+}
+
+// loadCacheEntry 读取缓存及剩余过期时间，不存在或无法解析时返回 nil
+func loadCacheEntry(ctx context.Context, redisCache *cache.RedisCache, key string) (*cacheEntry, time.Duration) {
+	value, ttl, err := redisCache.GetValueAndTTL(ctx, key)
+	if err != nil {
+		logs.CtxError(ctx, "get cache err", "key", key, "error", err)
+		return nil, 0
+	}
+	if value == "" {
+		return nil, 0
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		logs.CtxWarn(ctx, "decode cache entry err", "key", key)
+		return nil, 0
+	}
+	return &entry, ttl
+}
+
+// waitCacheEntry 轮询等待其他实例写入缓存，超时返回 nil
+func waitCacheEntry(ctx context.Context, redisCache *cache.RedisCache, key string) (*cacheEntry, time.Duration) {
+	timer := time.NewTimer(cacheLockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, 0
+		case <-timer.C:
+			return nil, 0
+		case <-ticker.C:
+			if entry, ttl := loadCacheEntry(ctx, redisCache, key); entry != nil {
+				return entry, ttl
+			}
+		}
+	}
+}
+
+// tryCacheLock 获取跨实例的回源锁，返回持有者 token，Redis 异常时视为获取成功，避免请求被阻塞
+func tryCacheLock(ctx context.Context, redisCache *cache.RedisCache, key string) (string, bool) {
+	token := uuid.NewString()
+	ok, err := redisCache.SetNX(ctx, "CACHE_LOCK:"+key, token, cacheLockTTL)
+	if err != nil {
+		logs.CtxError(ctx, "acquire cache lock err", "key", key, "error", err)
+		return "", true
+	}
+	return token, ok
+}
+
+// releaseCacheLock 只释放自己持有的锁，回源超过 cacheLockTTL 时锁可能已被其他实例获取
+func releaseCacheLock(ctx context.Context, redisCache *cache.RedisCache, key, token string) {
+	if token == "" {
+		return
+	}
+	if _, err := redisCache.DeleteIfEqual(context.WithoutCancel(ctx), "CACHE_LOCK:"+key, token); err != nil {
+		logs.CtxError(ctx, "release cache lock err", "key", key, "error", err)
+	}
+}
+
+// flightGroup 合并同一实例内相同 key 的并发请求
+// 与 singleflight 不同，handler panic 时只在执行的请求中向上抛出，等待的请求拿到 nil 后自行执行
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg     sync.WaitGroup
+	result *flightResult
+}
+
+func (g *flightGroup) Do(key string, fn func() *flightResult) (*flightResult, bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.result, true
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.result = fn()
+	return call.result, false
+}
+
+func buildCacheRules(conf *config.Cache) []*cacheRule {
+	defaultMethods := []string{http.MethodGet, http.MethodPost}
+	var rules []*cacheRule
+	for _, r := range conf.Rules {
+		rule := &cacheRule{
+			paths:   newPathMatcher(r.GetPath()),
+			methods: defaultMethods,
+			expire:  conf.GetExpire(),
+			stale:   conf.GetStaleWhileRevalidate(),
+			vary:    conf.GetVary(),
+		}
+		if len(r.Methods) > 0 {
+			rule.methods = make([]string, 0, len(r.Methods))
+			for _, m := range r.Methods {
+				rule.methods = append(rule.methods, strings.ToUpper(m))
+			}
+		}
+		if r.Expire != nil {
+			rule.expire = *r.Expire
+		}
+		if r.StaleWhileRevalidate != nil {
+			rule.stale = *r.StaleWhileRevalidate
+		}
+		if r.Vary != nil {
+			rule.vary = r.Vary
+		}
+		rule.tags = r.Tags
+		rules = append(rules, rule)
+	}
+	for _, pattern := range conf.GetNeedCache() {
+		rules = append(rules, &cacheRule{
+			paths:   newPathMatcher(pattern),
+			methods: defaultMethods,
+			expire:  conf.GetExpire(),
+			stale:   conf.GetStaleWhileRevalidate(),
+			vary:    conf.GetVary(),
+		})
+	}
+	return rules
+}
+
+// cacheKey 计算缓存 key：CACHE:<method>:<path>:<md5(query, vary, body)>
+func cacheKey(c *gin.Context, rule *cacheRule) (string, error) {
+	var buf bytes.Buffer
+	// url.Values.Encode 会按参数名排序，参数顺序不同的请求可以命中同一个缓存
+	buf.WriteString(normalizeQuery(c.Request.URL.Query()))
+	for _, name := range rule.vary {
+		buf.WriteString("\n")
+		buf.WriteString(name)
+		buf.WriteString("=")
+		buf.WriteString(varyValue(c, name))
+	}
+	if c.Request.Method == http.MethodPost {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		buf.WriteString("\n")
+		buf.Write(body)
+	}
+	return fmt.Sprintf("CACHE:%s:%s:%s", c.Request.Method, c.Request.URL.Path, crypro.Md5(buf.Bytes())), nil
+}
+
+func normalizeQuery(query url.Values) string {
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	return query.Encode()
+}
+
+// varyValue userId 取 Auth 中间件设置的当前用户，其余按请求头处理
+func varyValue(c *gin.Context, name string) string {
+	if name == "userId" {
+		if userId, ok := c.Get("userId"); ok {
+			return fmt.Sprint(userId)
+		}
+		return ""
+	}
+	return c.GetHeader(name)
+}
+
+// isCacheable 只缓存 2xx 响应，JSON 响应还要求业务码为 res.OK
+func isCacheable(entry *cacheEntry) bool {
+	if entry.Status < http.StatusOK || entry.Status >= http.StatusMultipleChoices {
+		return false
+	}
+	if strings.HasPrefix(entry.ContentType, gin.MIMEJSON) {
+		var result struct {
+			Code *int `json:"code"`
+		}
+		if err := json.Unmarshal(entry.Body, &result); err != nil {
+			logs.Errorf("cache json Unmarshal err: %v", err)
+			return false
+		}
+		if result.Code != nil && *result.Code != res.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCacheEntry 写出响应，可缓存的响应会补充 ETag、Cache-Control 和 Vary 响应头
+// 请求携带的 If-None-Match 与 ETag 一致时返回 304
+func writeCacheEntry(c *gin.Context, rule *cacheRule, entry *cacheEntry, ttl time.Duration, state string, cacheable bool) {
+	header := c.Writer.Header()
+	header.Set("X-Cache", state)
+	if cacheable {
+		scope := "public"
+		var vary []string
+		for _, name := range rule.vary {
+			if name == "userId" {
+				scope = "private"
+				continue
+			}
+			vary = append(vary, name)
+		}
+		if len(vary) > 0 {
+			header.Set("Vary", strings.Join(vary, ", "))
+		}
+		if ttl < 0 {
+			ttl = 0
+		}
+		header.Set("ETag", entry.ETag)
+		header.Set("Cache-Control", scope+", max-age="+strconv.Itoa(int(ttl.Seconds())))
+		if match := c.GetHeader("If-None-Match"); match != "" && match == entry.ETag {
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+	}
+	// HIT 和 STALE 从缓存回放，需要还原 Content-Type；MISS 的响应头已经由 handler 写好
+	if state != "MISS" {
+		c.Data(entry.Status, entry.ContentType, entry.Body)
+		return
+	}
+	c.Status(entry.Status)
+	if len(entry.Body) == 0 {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if _, err := c.Writer.Write(entry.Body); err != nil {
+		logs.CtxError(c.Request.Context(), "write response err", "error", err)
+	}
+}
@@ -0,0 +1,196 @@
+package midd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/cache"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/database"
+	"github.com/zhangc-zwl/thunder/db"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+// useTestRedis 使用 miniredis 作为全局 Redis，测试结束后还原
+func useTestRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	old := database.GetRedis()
+	database.SetRedis(&db.Redis{Client: client})
+	t.Cleanup(func() {
+		database.SetRedis(old)
+		_ = client.Close()
+	})
+	return mr
+}
+
+type cacheServer struct {
+	engine *gin.Engine
+	calls  int
+}
+
+func newCacheServer(conf *config.Cache) *cacheServer {
+	gin.SetMode(gin.TestMode)
+	s := &cacheServer{engine: gin.New()}
+	s.engine.Use(func(c *gin.Context) {
+		if userId := c.GetHeader("X-User"); userId != "" {
+			c.Set("userId", userId)
+		}
+	}, Cache(conf))
+	s.engine.GET("/articles", func(c *gin.Context) {
+		s.calls++
+		c.JSON(http.StatusOK, gin.H{"code": 200, "data": c.Query("page")})
+	})
+	s.engine.GET("/failed", func(c *gin.Context) {
+		s.calls++
+		c.JSON(http.StatusOK, gin.H{"code": 500})
+	})
+	s.engine.GET("/text", func(c *gin.Context) {
+		s.calls++
+		c.String(http.StatusCreated, "hello")
+	})
+	return s
+}
+
+func (s *cacheServer) get(path string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	mr := useTestRedis(t)
+	s := newCacheServer(&config.Cache{
+		Rules: []*config.CacheRule{
+			{Path: gptr.Of("/articles"), Methods: []string{"get"}},
+			{Path: gptr.Of("/failed")},
+			{Path: gptr.Of("/text")},
+		},
+	})
+	first := s.get("/articles?page=1&size=10")
+	if first.Header().Get("X-Cache") != "MISS" || s.calls != 1 {
+		t.Fatalf("first request should miss, got %s", first.Header().Get("X-Cache"))
+	}
+	// 未配置 expire 时默认缓存 5 分钟
+	if cc := first.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+		t.Fatalf("default expire should be 300s, got %q", cc)
+	}
+	if ttl := mr.TTL(mr.Keys()[0]); ttl != 300*time.Second {
+		t.Fatalf("default redis ttl should be 300s, got %v", ttl)
+	}
+	// 查询参数顺序不同也命中同一个缓存
+	second := s.get("/articles?size=10&page=1")
+	if second.Header().Get("X-Cache") != "HIT" || s.calls != 1 {
+		t.Fatalf("second request should hit, got %s", second.Header().Get("X-Cache"))
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Fatalf("hit should replay body and content type, got %q %q", second.Header().Get("Content-Type"), second.Body.String())
+	}
+	etag := second.Header().Get("ETag")
+	if w := s.get("/articles?page=1&size=10", "If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("matching etag should return 304, got %d", w.Code)
+	}
+
+	// 业务码不是 200 的 JSON 响应不缓存
+	s.get("/failed")
+	s.get("/failed")
+	if s.calls != 3 {
+		t.Fatalf("failed responses should not be cached, calls=%d", s.calls)
+	}
+	// 非 JSON 响应原样缓存状态码和 Content-Type
+	s.get("/text")
+	w := s.get("/text")
+	if w.Header().Get("X-Cache") != "HIT" || w.Code != http.StatusCreated || w.Body.String() != "hello" || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("text hit: %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+func TestCacheVaryUser(t *testing.T) {
+	useTestRedis(t)
+	s := newCacheServer(&config.Cache{
+		Expire: gptr.Of(int64(60)),
+		Rules:  []*config.CacheRule{{Path: gptr.Of("/articles"), Vary: []string{"userId"}}},
+	})
+	w := s.get("/articles", "X-User", "1")
+	if w.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Fatalf("per-user cache should be private, got %q", w.Header().Get("Cache-Control"))
+	}
+	s.get("/articles", "X-User", "1")
+	s.get("/articles", "X-User", "2")
+	if s.calls != 2 {
+		t.Fatalf("each user should have its own entry, calls=%d", s.calls)
+	}
+}
+
+func TestCacheWithoutRedis(t *testing.T) {
+	old := database.GetRedis()
+	database.SetRedis(nil)
+	t.Cleanup(func() { database.SetRedis(old) })
+	s := newCacheServer(&config.Cache{Rules: []*config.CacheRule{{Path: gptr.Of("/articles")}}})
+	for i := 0; i < 2; i++ {
+		w := s.get("/articles")
+		if w.Code != http.StatusOK || w.Header().Get("X-Cache") != "" {
+			t.Fatalf("without redis the request should bypass the cache, got %d %q", w.Code, w.Header().Get("X-Cache"))
+		}
+	}
+	if s.calls != 2 {
+		t.Fatalf("handler should run on every request, calls=%d", s.calls)
+	}
+}
+
+func TestCacheStale(t *testing.T) {
+	mr := useTestRedis(t)
+	s := newCacheServer(&config.Cache{
+		Expire:               gptr.Of(int64(10)),
+		StaleWhileRevalidate: gptr.Of(int64(60)),
+		Rules:                []*config.CacheRule{{Path: gptr.Of("/articles")}},
+	})
+	s.get("/articles")
+	keys := mr.Keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected one cache key, got %v", keys)
+	}
+	key := keys[0]
+	mr.FastForward(11 * time.Second)
+
+	// 其他实例正在刷新，返回旧数据并还原 Content-Type
+	lockKey := "CACHE_LOCK:" + key
+	if err := mr.Set(lockKey, "other"); err != nil {
+		t.Fatal(err)
+	}
+	w := s.get("/articles")
+	if w.Header().Get("X-Cache") != "STALE" || s.calls != 1 {
+		t.Fatalf("expected stale response, got %s", w.Header().Get("X-Cache"))
+	}
+	if w.Code != http.StatusOK || w.Body.String() != `{"code":200,"data":""}` || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("stale replay: %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	// 不是自己持有的锁不会被释放
+	releaseCacheLock(context.Background(), cache.NewRedisCache(), key, "mine")
+	if v, _ := mr.Get(lockKey); v != "other" {
+		t.Fatalf("lock held by another instance was released")
+	}
+
+	// 锁释放后由一个请求回源刷新，刷新完成释放自己的锁
+	mr.Del(lockKey)
+	if w := s.get("/articles"); w.Header().Get("X-Cache") != "MISS" || s.calls != 2 {
+		t.Fatalf("expected refresh, got %s", w.Header().Get("X-Cache"))
+	}
+	if mr.Exists(lockKey) {
+		t.Fatal("refresh lock should be released")
+	}
+	if w := s.get("/articles"); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("refreshed entry should hit, got %s", w.Header().Get("X-Cache"))
+	}
+}
@@ -0,0 +1,186 @@
+package midd
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/tools/randoms"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+// limitResult 一次限流判断的结果
+type limitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// limiter 限流器，key 为已经拼接好维度的完整 key
+type limiter interface {
+	Allow(ctx context.Context, key string, rule *limitRule) (limitResult, error)
+}
+
+// 令牌桶：以 limit/window 的速率补充令牌，桶容量为 limit
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// 滑动窗口：使用有序集合记录窗口内每次请求的时间
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local retry = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, 0, retry}
+`)
+
+// redisLimiter 基于 Redis Lua 脚本的限流器，多实例之间共享计数
+type redisLimiter struct {
+	client redis.Scripter
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, rule *limitRule) (limitResult, error) {
+	now := time.Now().UnixMilli()
+	window := rule.window.Milliseconds()
+	var (
+		values []int64
+		err    error
+	)
+	if rule.algorithm == AlgorithmTokenBucket {
+		rate := float64(rule.limit) / float64(window)
+		values, err = tokenBucketScript.Run(ctx, l.client, []string{key},
+			rule.limit, strconv.FormatFloat(rate, 'f', -1, 64), now, window*2).Int64Slice()
+	} else {
+		member := strconv.FormatInt(now, 10) + "-" + randoms.GenerateTicket()
+		values, err = slidingWindowScript.Run(ctx, l.client, []string{key},
+			rule.limit, window, now, member).Int64Slice()
+	}
+	if err != nil {
+		return limitResult{}, err
+	}
+	return limitResult{
+		Allowed:    values[0] == 1,
+		Limit:      rule.limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// memoryLimiter 进程内限流器，未配置 Redis 时使用，只对单实例生效
+type memoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens   float64
+	last     time.Time
+	hits     []time.Time
+	expireAt time.Time
+}
+
+func newMemoryLimiter() *memoryLimiter {
+	return &memoryLimiter{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, rule *limitRule) (limitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(rule.limit), last: now}
+		l.buckets[key] = b
+	}
+	result := limitResult{Limit: rule.limit}
+	if rule.algorithm == AlgorithmTokenBucket {
+		rate := float64(rule.limit) / float64(rule.window)
+		b.tokens = math.Min(float64(rule.limit), b.tokens+float64(now.Sub(b.last))*rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rate))
+		}
+		result.Remaining = int(b.tokens)
+		b.expireAt = now.Add(rule.window * 2)
+		return result, nil
+	}
+	// 清理窗口外的请求记录
+	start := now.Add(-rule.window)
+	i := 0
+	for i < len(b.hits) && !b.hits[i].After(start) {
+		i++
+	}
+	b.hits = b.hits[i:]
+	if len(b.hits) < rule.limit {
+		b.hits = append(b.hits, now)
+		result.Allowed = true
+		result.Remaining = rule.limit - len(b.hits)
+	} else {
+		result.RetryAfter = b.hits[0].Add(rule.window).Sub(now)
+	}
+	b.expireAt = now.Add(rule.window)
+	return result, nil
+}
+
+// sweep 每分钟清理一次过期的 key，避免内存无限增长
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.After(b.expireAt) {
+			delete(l.buckets, key)
+		}
+	}
+}
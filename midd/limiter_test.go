@@ -0,0 +1,57 @@
+package midd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhangc-zwl/thunder/database"
+	"github.com/zhangc-zwl/thunder/db"
+)
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	l := newMemoryLimiter()
+	rule := &limitRule{algorithm: AlgorithmSlidingWindow, limit: 2, window: time.Minute}
+	for i := 0; i < 2; i++ {
+		result, _ := l.Allow(context.Background(), "k", rule)
+		if !result.Allowed {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	result, _ := l.Allow(context.Background(), "k", rule)
+	if result.Allowed || result.RetryAfter <= 0 {
+		t.Fatalf("third request should be limited, got %+v", result)
+	}
+}
+
+func TestMemoryLimiterTokenBucket(t *testing.T) {
+	l := newMemoryLimiter()
+	rule := &limitRule{algorithm: AlgorithmTokenBucket, limit: 1, window: 50 * time.Millisecond}
+	if result, _ := l.Allow(context.Background(), "k", rule); !result.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+	if result, _ := l.Allow(context.Background(), "k", rule); result.Allowed {
+		t.Fatal("second request should be limited")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := l.Allow(context.Background(), "k", rule); !result.Allowed {
+		t.Fatal("request should be allowed after refill")
+	}
+}
+
+func TestSharedLimiterFollowsRedis(t *testing.T) {
+	old := database.GetRedis()
+	defer database.SetRedis(old)
+	database.SetRedis(nil)
+	if sharedLimiter() != nil {
+		t.Fatal("without redis the limiter should fall back to memory")
+	}
+	// Redis 在第一次请求之后才连接成功，也要切换到共享限流器
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer client.Close()
+	database.SetRedis(&db.Redis{Client: client})
+	if _, ok := sharedLimiter().(*redisLimiter); !ok {
+		t.Fatal("limiter should switch to redis once it is connected")
+	}
+}
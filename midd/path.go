@@ -0,0 +1,38 @@
+package midd
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zhangc-zwl/thunder/logs"
+)
+
+// pathMatcher 预编译的路径规则，** 匹配任意字符，其余部分按正则表达式处理
+// 中间件构建时编译一次，请求时只做匹配
+type pathMatcher []*regexp.Regexp
+
+// newPathMatcher 编译路径规则，非法的规则记录日志后永远不匹配
+func newPathMatcher(patterns ...string) pathMatcher {
+	m := make(pathMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^" + strings.ReplaceAll(pattern, "**", ".*") + "$")
+		if err != nil {
+			logs.Warnf("invalid path pattern %q: %v", pattern, err)
+		}
+		m = append(m, re)
+	}
+	return m
+}
+
+// match 判断 path 是否匹配任一规则，没有配置规则时返回 empty
+func (m pathMatcher) match(path string, empty bool) bool {
+	if len(m) == 0 {
+		return empty
+	}
+	for _, re := range m {
+		if re != nil && re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package midd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Subject 当前请求的访问主体
+type Subject struct {
+	UserId      string
+	Roles       []string
+	Permissions []string
+}
+
+// HasRole 是否拥有任一角色
+func (s *Subject) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		for _, r := range s.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission 是否拥有权限，支持 * 和 article:* 形式的通配权限
+func (s *Subject) HasPermission(permission string) bool {
+	for _, p := range s.Permissions {
+		if p == "*" || p == permission {
+			return true
+		}
+		if strings.HasSuffix(p, ":*") && strings.HasPrefix(permission, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyProvider 权限来源，用于补全主体的角色和权限
+// token 中的角色和权限会先放入 Subject，provider 可以在此基础上从数据库、策略文件等位置追加
+type PolicyProvider interface {
+	Load(ctx context.Context, subject *Subject) error
+}
+
+// PolicyFunc 将普通函数适配为 PolicyProvider，便于从数据库加载权限
+type PolicyFunc func(ctx context.Context, subject *Subject) error
+
+func (f PolicyFunc) Load(ctx context.Context, subject *Subject) error {
+	return f(ctx, subject)
+}
+
+// FilePolicy Casbin 风格的策略文件
+//
+//	p, admin, article:*
+//	p, editor, article:write
+//	g, 10001, admin
+//
+// p 行定义角色拥有的权限，g 行定义用户拥有的角色，# 开头为注释
+type FilePolicy struct {
+	rolePermissions map[string][]string
+	userRoles       map[string][]string
+}
+
+// NewFilePolicy 加载策略文件
+func NewFilePolicy(path string) (*FilePolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	policy := &FilePolicy{
+		rolePermissions: make(map[string][]string),
+		userRoles:       make(map[string][]string),
+	}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("policy %s:%d: expect 3 fields", path, line)
+		}
+		switch fields[0] {
+		case "p":
+			policy.rolePermissions[fields[1]] = append(policy.rolePermissions[fields[1]], fields[2])
+		case "g":
+			policy.userRoles[fields[1]] = append(policy.userRoles[fields[1]], fields[2])
+		default:
+			return nil, fmt.Errorf("policy %s:%d: unknown type %s", path, line, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *FilePolicy) Load(_ context.Context, subject *Subject) error {
+	subject.Roles = append(subject.Roles, p.userRoles[subject.UserId]...)
+	for _, role := range subject.Roles {
+		subject.Permissions = append(subject.Permissions, p.rolePermissions[role]...)
+	}
+	return nil
+}
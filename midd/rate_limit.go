@@ -0,0 +1,139 @@
+package midd
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/database"
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/res"
+)
+
+// limitRule 解析后的限流规则
+type limitRule struct {
+	index     int
+	path      string
+	paths     pathMatcher
+	method    string
+	keyBy     string
+	header    string
+	algorithm string
+	limit     int
+	window    time.Duration
+}
+
+// RateLimit 限流中间件
+// 按 config.RateLimit 中的规则对匹配的路径限流，限流维度可以是客户端 IP、Auth 中间件设置的 userId 或指定的请求头
+// 配置了 Redis 时使用 Lua 脚本原子计数，多实例共享限额；否则退化为进程内限流
+// 需要按 userId 限流时，应保证 RateLimit 挂载在 Auth 之后
+func RateLimit(conf *config.RateLimit) gin.HandlerFunc {
+	rules := make([]*limitRule, 0, len(conf.Rules))
+	for i, r := range conf.Rules {
+		rule := &limitRule{
+			index:     i,
+			path:      r.GetPath(),
+			paths:     newPathMatcher(r.GetPath()),
+			method:    strings.ToUpper(r.GetMethod()),
+			keyBy:     r.GetKeyBy(),
+			header:    r.GetHeader(),
+			algorithm: r.GetAlgorithm(),
+			limit:     r.GetLimit(),
+			window:    r.GetWindow(),
+		}
+		if rule.limit <= 0 || rule.window <= 0 {
+			panic(fmt.Sprintf("rate limit rule %s: limit and window must be positive", rule.path))
+		}
+		if rule.algorithm != AlgorithmTokenBucket && rule.algorithm != AlgorithmSlidingWindow {
+			panic(fmt.Sprintf("rate limit rule %s: unknown algorithm %s", rule.path, rule.algorithm))
+		}
+		rules = append(rules, rule)
+	}
+	prefix := conf.GetPrefix()
+	fallback := newMemoryLimiter()
+	return func(c *gin.Context) {
+		if !conf.GetEnable() {
+			c.Next()
+			return
+		}
+		// Redis 可能晚于中间件初始化或在降级模式下重连成功，因此每次请求都重新确定使用哪个限流器
+		shared := sharedLimiter()
+		var current *limitResult
+		for _, rule := range rules {
+			if rule.method != "" && rule.method != c.Request.Method {
+				continue
+			}
+			if !rule.paths.match(c.Request.URL.Path, false) {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d:%s", prefix, rule.index, limitIdentity(c, rule))
+			var (
+				result limitResult
+				err    error
+			)
+			if shared != nil {
+				result, err = shared.Allow(c.Request.Context(), key, rule)
+				if err != nil {
+					logs.CtxError(c.Request.Context(), "rate limit redis err, fallback to memory", "error", err)
+					result, err = fallback.Allow(c.Request.Context(), key, rule)
+				}
+			} else {
+				result, err = fallback.Allow(c.Request.Context(), key, rule)
+			}
+			if err != nil {
+				continue
+			}
+			if current == nil || !result.Allowed || result.Remaining < current.Remaining {
+				current = &result
+			}
+			if !result.Allowed {
+				break
+			}
+		}
+		if current == nil {
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(current.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(current.Remaining))
+		if !current.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(current.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, res.Result{
+				Code: errs.ErrTooManyRequests.Code,
+				Msg:  errs.ErrTooManyRequests.Msg,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitIdentity 根据规则获取限流维度的值，取不到时退化为客户端 IP
+func limitIdentity(c *gin.Context, rule *limitRule) string {
+	switch rule.keyBy {
+	case "user":
+		if userId, ok := c.Get("userId"); ok {
+			return fmt.Sprintf("user:%v", userId)
+		}
+	case "header":
+		if value := c.GetHeader(rule.header); value != "" {
+			return "header:" + value
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// sharedLimiter Redis 已连接时返回多实例共享的限流器，否则返回 nil
+func sharedLimiter() limiter {
+	cli := database.GetRedis()
+	if cli == nil || cli.Client == nil {
+		return nil
+	}
+	return &redisLimiter{client: cli.Client}
+}
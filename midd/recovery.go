@@ -0,0 +1,79 @@
+package midd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/errs"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/res"
+)
+
+// Recovery 捕获 panic 的中间件
+// panic 信息和堆栈通过 logs.CtxError 输出（带 request_id），并返回与其他错误一致的 res.Result
+// 只有在 debug 模式且开启了 server.showStack 时，才会在响应的 data 中返回堆栈
+func Recovery(conf *config.Server) gin.HandlerFunc {
+	showStack := conf.GetShowStack() && conf.GetMode() == gin.DebugMode
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			ctx := c.Request.Context()
+			stack := string(debug.Stack())
+			// 客户端断开连接时无法再写入响应
+			if isBrokenPipe(r) {
+				logs.CtxWarn(ctx, "connection broken", "error", fmt.Sprint(r), "path", c.Request.URL.Path)
+				_ = c.Error(fmt.Errorf("%v", r))
+				c.Abort()
+				return
+			}
+			logs.CtxError(ctx, "panic recovered",
+				"error", fmt.Sprint(r),
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"stack", stack,
+			)
+			result := res.Result{
+				Code: errs.ErrInternal.Code,
+				Msg:  errs.ErrInternal.Msg,
+			}
+			if showStack {
+				result.Msg = fmt.Sprint(r)
+				result.Data = strings.Split(stack, "\n")
+			}
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, result)
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenPipe 判断 panic 是否由客户端断开连接引起
+func isBrokenPipe(r any) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if errors.As(ne, &se) {
+		return errors.Is(se.Err, syscall.EPIPE) || errors.Is(se.Err, syscall.ECONNRESET)
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package midd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+)
+
+// Factory 根据全局配置创建中间件
+// 返回 nil 表示当前配置下该中间件无需挂载
+type Factory func(conf *config.Config) gin.HandlerFunc
+
+var (
+	factories = make(map[string]Factory)
+	mu        sync.RWMutex
+)
+
+func init() {
+	Register("cors", func(conf *config.Config) gin.HandlerFunc {
+		if len(conf.Server.GetCros()) == 0 {
+			return nil
+		}
+		return Cors(conf.Server)
+	})
+	Register("auth", func(conf *config.Config) gin.HandlerFunc {
+		if !conf.Auth.GetIsAuth() {
+			return nil
+		}
+		return Auth(conf.Auth)
+	})
+	Register("authz", func(conf *config.Config) gin.HandlerFunc {
+		if !conf.Authz.GetEnable() {
+			return nil
+		}
+		return Authorize(conf.Authz)
+	})
+	Register("tenant", func(conf *config.Config) gin.HandlerFunc {
+		if !conf.Tenant.GetEnable() {
+			return nil
+		}
+		return Tenant(conf.Tenant)
+	})
+	Register("rateLimit", func(conf *config.Config) gin.HandlerFunc {
+		if !conf.RateLimit.GetEnable() || len(conf.RateLimit.Rules) == 0 {
+			return nil
+		}
+		return RateLimit(conf.RateLimit)
+	})
+	Register("cache", func(conf *config.Config) gin.HandlerFunc {
+		if !conf.Cache.IsEnable() {
+			return nil
+		}
+		return Cache(conf.Cache)
+	})
+}
+
+// Register 注册一个具名中间件，同名注册会覆盖之前的实现
+// 业务方可以在 init 中注册自己的中间件，再通过配置文件启用
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// Lookup 按名称查找已注册的中间件
+func Lookup(name string) (Factory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	factory, ok := factories[name]
+	return factory, ok
+}
+
+// Build 根据 conf.Middlewares 按 order 构建中间件管道
+// 配置了 paths/excludes 的中间件只会在匹配的路径上执行
+func Build(conf *config.Config) ([]gin.HandlerFunc, error) {
+	items := make([]*config.Middleware, 0, len(conf.Middlewares))
+	for _, m := range conf.Middlewares {
+		if m != nil && m.GetEnable() {
+			items = append(items, m)
+		}
+	}
+	// 稳定排序，order 相同时保持配置文件中的顺序
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].GetOrder() < items[j].GetOrder()
+	})
+	var handlers []gin.HandlerFunc
+	for _, m := range items {
+		factory, ok := Lookup(m.GetName())
+		if !ok {
+			return nil, fmt.Errorf("middleware %q is not registered", m.GetName())
+		}
+		handler := factory(conf)
+		if handler == nil {
+			continue
+		}
+		handlers = append(handlers, Scope(handler, m.Paths, m.Excludes))
+	}
+	return handlers, nil
+}
+
+// Use 在路由组上按名称挂载中间件，用于在代码中直接限定中间件的作用范围
+//
+//	api := engine.Group("/api")
+//	midd.Use(api, conf, "auth", "cache")
+func Use(group gin.IRoutes, conf *config.Config, names ...string) error {
+	for _, name := range names {
+		factory, ok := Lookup(name)
+		if !ok {
+			return fmt.Errorf("middleware %q is not registered", name)
+		}
+		if handler := factory(conf); handler != nil {
+			group.Use(handler)
+		}
+	}
+	return nil
+}
+
+// Scope 将中间件限定在匹配 paths 且不匹配 excludes 的请求上
+// paths 为空表示对所有路径生效
+func Scope(handler gin.HandlerFunc, paths []string, excludes []string) gin.HandlerFunc {
+	if len(paths) == 0 && len(excludes) == 0 {
+		return handler
+	}
+	include, exclude := newPathMatcher(paths...), newPathMatcher(excludes...)
+	return func(c *gin.Context) {
+		if !include.match(c.Request.URL.Path, true) || exclude.match(c.Request.URL.Path, false) {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
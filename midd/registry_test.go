@@ -0,0 +1,74 @@
+package midd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestPathMatcher(t *testing.T) {
+	m := newPathMatcher("/api/**", "/health", "/bad/(**")
+	cases := map[string]bool{
+		"/api/users":   true,
+		"/api/":        true,
+		"/health":      true,
+		"/health/live": false,
+		"/bad/x":       false,
+		"/other":       false,
+	}
+	for path, want := range cases {
+		if got := m.match(path, false); got != want {
+			t.Errorf("match(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !newPathMatcher().match("/any", true) || newPathMatcher().match("/any", false) {
+		t.Fatal("empty matcher should return the empty value")
+	}
+}
+
+func TestBuildOrderAndScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var trace []string
+	for _, name := range []string{"test-a", "test-b", "test-c"} {
+		name := name
+		Register(name, func(*config.Config) gin.HandlerFunc {
+			return func(c *gin.Context) {
+				trace = append(trace, name)
+				c.Next()
+			}
+		})
+	}
+	conf := &config.Config{Middlewares: []*config.Middleware{
+		{Name: gptr.Of("test-c"), Order: gptr.Of(2)},
+		{Name: gptr.Of("test-a"), Order: gptr.Of(1), Paths: []string{"/api/**"}, Excludes: []string{"/api/public/**"}},
+		{Name: gptr.Of("test-b"), Order: gptr.Of(1), Enable: gptr.Of(false)},
+	}}
+	handlers, err := Build(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(handlers...)
+	engine.GET("/*path", func(*gin.Context) {})
+	for path, want := range map[string]string{
+		"/api/users":      "test-a,test-c",
+		"/api/public/doc": "test-c",
+		"/home":           "test-c",
+	} {
+		trace = nil
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(trace, ","); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+
+	conf.Middlewares = append(conf.Middlewares, &config.Middleware{Name: gptr.Of("test-missing")})
+	if _, err := Build(conf); err == nil {
+		t.Fatal("unregistered middleware should fail")
+	}
+}
@@ -0,0 +1,15 @@
+package midd
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/db"
+)
+
+// StickyPrimary 在请求的 context 中开启写后读主库
+// 配置了从库时，同一个请求内执行过写操作后，后续读请求都使用主库，查询需要通过 WithContext(c.Request.Context()) 传入 context
+func StickyPrimary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(db.WithSticky(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package midd
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/logs"
+	"github.com/zhangc-zwl/thunder/tenant"
+)
+
+// Tenant 解析请求所属的租户并写入 c.Request 的 context，GORM 查询通过 WithContext 传入后自动按租户过滤
+// jwt 来源读取 Auth 中间件设置的 tenantId，需要挂载在 Auth 之后；
+// token 中带有租户时，header 和 subdomain 解析出的租户必须与之一致，防止伪造请求头访问其他租户；
+// token 中没有租户或匿名请求时，只有开启 trustUnverified 才使用 header 和 subdomain，
+// 否则已登录但 token 中没有租户的请求返回 403，匿名请求视为没有租户
+func Tenant(conf *config.Tenant) gin.HandlerFunc {
+	ignores := newPathMatcher(conf.GetIgnores()...)
+	trust := conf.GetTrustUnverified()
+	return func(c *gin.Context) {
+		if ignores.match(c.Request.URL.Path, false) {
+			c.Next()
+			return
+		}
+		claimed := c.GetString("tenantId")
+		if claimed == "" && !trust {
+			if _, ok := c.Get("userId"); ok {
+				logs.CtxWarn(c.Request.Context(), "tenant missing in token")
+				c.JSON(http.StatusForbidden, gin.H{"error": "Tenant is missing in token"})
+				c.Abort()
+				return
+			}
+		}
+		var id string
+		for _, source := range conf.GetSources() {
+			value := resolveTenant(c, conf, source)
+			if value == "" {
+				continue
+			}
+			// 没有经过 token 校验的租户不可信
+			if claimed == "" && !trust {
+				continue
+			}
+			if claimed != "" && value != claimed {
+				logs.CtxWarn(c.Request.Context(), "tenant mismatch", "source", source, "tenant", value, "claimed", claimed)
+				c.JSON(http.StatusForbidden, gin.H{"error": "Tenant mismatch"})
+				c.Abort()
+				return
+			}
+			if id == "" {
+				id = value
+			}
+		}
+		if id == "" {
+			if conf.GetRequired() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Tenant is missing"})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		c.Set("tenantId", id)
+		ctx := tenant.WithTenant(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logs.WithContext(ctx, "tenantId", id))
+		c.Next()
+	}
+}
+
+func resolveTenant(c *gin.Context, conf *config.Tenant, source string) string {
+	switch source {
+	case config.TenantSourceJwt:
+		return c.GetString("tenantId")
+	case config.TenantSourceHeader:
+		return strings.TrimSpace(c.GetHeader(conf.GetHeader()))
+	case config.TenantSourceSubdomain:
+		return subdomain(c.Request.Host, conf.GetDomain())
+	}
+	return ""
+}
+
+// subdomain 返回 host 中主域名前的一级子域名，acme.example.com 返回 acme，www 不视为租户
+func subdomain(host, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	suffix := "." + strings.ToLower(strings.TrimPrefix(domain, "."))
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	sub := strings.TrimSuffix(host, suffix)
+	if sub == "" || sub == "www" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
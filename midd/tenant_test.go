@@ -0,0 +1,98 @@
+package midd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tenant"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+// serveTenant claimed 为 token 中的租户，为 "-" 时表示已登录但 token 中没有租户
+func serveTenant(conf *config.Tenant, claimed string, req *http.Request) (int, string) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var resolved string
+	engine.Use(func(c *gin.Context) {
+		if claimed != "" {
+			c.Set("userId", "u1")
+		}
+		if claimed != "" && claimed != "-" {
+			c.Set("tenantId", claimed)
+		}
+	}, Tenant(conf))
+	engine.GET("/*path", func(c *gin.Context) {
+		resolved, _ = tenant.FromContext(c.Request.Context())
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code, resolved
+}
+
+func TestTenantSources(t *testing.T) {
+	conf := &config.Tenant{
+		Sources: []string{config.TenantSourceJwt, config.TenantSourceHeader, config.TenantSourceSubdomain},
+		Domain:  gptr.Of("example.com"),
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://acme.example.com:8080/orders", nil)
+	if code, id := serveTenant(conf, "t1", req); code != http.StatusForbidden || id != "" {
+		t.Fatalf("subdomain must match token tenant: code=%d tenant=%q", code, id)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://www.example.com/orders", nil)
+	req.Header.Set("X-Tenant-ID", "t1")
+	if code, id := serveTenant(conf, "t1", req); code != http.StatusOK || id != "t1" {
+		t.Fatalf("jwt and header: code=%d tenant=%q", code, id)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://www.example.com/orders", nil)
+	req.Header.Set("X-Tenant-ID", "t2")
+	if code, _ := serveTenant(conf, "t1", req); code != http.StatusForbidden {
+		t.Fatalf("header must match token tenant, got %d", code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://www.example.com/orders", nil)
+	if code, _ := serveTenant(conf, "", req); code != http.StatusBadRequest {
+		t.Fatalf("missing tenant should be rejected, got %d", code)
+	}
+
+	conf.Required = gptr.Of(false)
+	if code, id := serveTenant(conf, "", req); code != http.StatusOK || id != "" {
+		t.Fatalf("optional tenant: code=%d tenant=%q", code, id)
+	}
+}
+
+func TestTenantUnverified(t *testing.T) {
+	conf := &config.Tenant{
+		Sources: []string{config.TenantSourceJwt, config.TenantSourceHeader, config.TenantSourceSubdomain},
+		Domain:  gptr.Of("example.com"),
+	}
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://acme.example.com/orders", nil)
+		req.Header.Set("X-Tenant-ID", "acme")
+		return req
+	}
+	// 默认不信任匿名请求的 header 和 subdomain
+	if code, id := serveTenant(conf, "", newReq()); code != http.StatusBadRequest || id != "" {
+		t.Fatalf("anonymous: code=%d tenant=%q", code, id)
+	}
+	// 已登录但 token 中没有租户，不能通过请求头选择租户
+	if code, id := serveTenant(conf, "-", newReq()); code != http.StatusForbidden || id != "" {
+		t.Fatalf("token without tenant: code=%d tenant=%q", code, id)
+	}
+	conf.Required = gptr.Of(false)
+	if code, _ := serveTenant(conf, "-", httptest.NewRequest(http.MethodGet, "/orders", nil)); code != http.StatusForbidden {
+		t.Fatalf("token without tenant should be rejected even when tenant is optional, got %d", code)
+	}
+
+	conf.TrustUnverified = gptr.Of(true)
+	if code, id := serveTenant(conf, "", newReq()); code != http.StatusOK || id != "acme" {
+		t.Fatalf("trusted anonymous: code=%d tenant=%q", code, id)
+	}
+	if code, id := serveTenant(conf, "-", newReq()); code != http.StatusOK || id != "acme" {
+		t.Fatalf("trusted token without tenant: code=%d tenant=%q", code, id)
+	}
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+// Usage migrate 子命令的用法
+const Usage = `usage: migrate <command> [flags]
+
+commands:
+  up [-n N]      apply pending migrations, all of them when -n is 0
+  down [-n N]    revert the last N applied migrations, default 1
+  status         show applied, pending, modified and missing migrations
+  create NAME    create an empty up/down migration pair in the migrations directory
+`
+
+// Run 执行 migrate 子命令，应用可以在自己的 main 中使用内嵌的迁移文件调用它，
+// 和 thunder migrate 命令的参数一致；dir 是 create 写入新文件的目录，create 不需要 Migrator
+//
+//	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+//		m := migrate.New(database.GetMysqlDB().GormDB, sub)
+//		if err := migrate.Run(ctx, m, "migrations", os.Args[2:], os.Stdout); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func Run(ctx context.Context, m *Migrator, dir string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate: missing command\n%s", Usage)
+	}
+	flags := pflag.NewFlagSet("migrate "+args[0], pflag.ContinueOnError)
+	flags.SetOutput(out)
+	steps := flags.IntP("steps", "n", 0, "number of migrations to apply or revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx, *steps)
+		for _, mg := range applied {
+			_, _ = fmt.Fprintf(out, "applied %d_%s\n", mg.Version, mg.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			_, _ = fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := m.Down(ctx, *steps)
+		for _, mg := range reverted {
+			_, _ = fmt.Fprintf(out, "reverted %d_%s\n", mg.Version, mg.Name)
+		}
+		return err
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range list {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, s.Status, appliedAt)
+		}
+		return w.Flush()
+	case "create":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("migrate: create requires a name\n%s", Usage)
+		}
+		up, down, err := Create(dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "created %s\ncreated %s\n", up, down)
+		return nil
+	default:
+		return fmt.Errorf("migrate: unknown command %q\n%s", args[0], Usage)
+	}
+}
@@ -0,0 +1,348 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/logs"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTable 迁移历史表名
+	DefaultTable = "schema_migrations"
+	// DefaultLockTimeout 等待其他实例释放迁移锁的最长时间
+	DefaultLockTimeout = time.Minute
+)
+
+const (
+	StatusApplied  = "applied"
+	StatusPending  = "pending"
+	StatusModified = "modified" // 已执行后文件内容被修改
+	StatusMissing  = "missing"  // 历史表中有记录但找不到迁移文件
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移被修改，需要新建迁移而不是修改旧文件
+	ErrChecksumMismatch = errors.New("migrate: checksum mismatch")
+	// ErrNoDown 迁移没有 down 文件，无法回滚
+	ErrNoDown = errors.New("migrate: no down migration")
+	// ErrLocked 等待迁移锁超时，其他实例正在迁移
+	ErrLocked = errors.New("migrate: lock timeout")
+)
+
+// Status 单个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Status    string
+	AppliedAt *time.Time
+}
+
+type history struct {
+	Version     int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name        string    `gorm:"size:255;not null"`
+	Checksum    string    `gorm:"size:64;not null"`
+	AppliedAt   time.Time `gorm:"not null"`
+	ExecutionMs int64     `gorm:"not null"`
+}
+
+// Migrator 执行 SQL 和 Go 迁移，每个迁移在单独的事务中执行并写入历史表，
+// MySQL 使用 GET_LOCK、Postgres 使用 pg_advisory_lock 保证同时只有一个实例在迁移
+// 注意 MySQL 的 DDL 会隐式提交，失败的迁移可能只执行了一部分
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	sub, _ := fs.Sub(migrations, "migrations")
+//	m := migrate.New(database.GetMysqlDB().GormDB, sub)
+//	applied, err := m.Up(ctx, 0)
+type Migrator struct {
+	db           *gorm.DB
+	fsys         fs.FS
+	goMigrations map[int64]*Migration
+	// Table 迁移历史表名，默认 DefaultTable
+	Table string
+	// LockTimeout 等待迁移锁的时间，默认 DefaultLockTimeout
+	LockTimeout time.Duration
+}
+
+// New 创建迁移器，fsys 根目录下为迁移文件，只使用 Go 迁移时可以为 nil
+func New(db *gorm.DB, fsys fs.FS) *Migrator {
+	return &Migrator{
+		db:           db,
+		fsys:         fsys,
+		goMigrations: make(map[int64]*Migration),
+		Table:        DefaultTable,
+		LockTimeout:  DefaultLockTimeout,
+	}
+}
+
+// Register 注册 Go 编写的迁移，适合数据修复等 SQL 不方便表达的迁移，版本号不能与 SQL 迁移重复
+func (m *Migrator) Register(version int64, name string, up, down Func) {
+	m.goMigrations[version] = &Migration{Version: version, Name: name, UpFunc: up, DownFunc: down}
+}
+
+// Dialect 当前数据库方言，mysql、postgres 等
+func (m *Migrator) Dialect() string {
+	return m.db.Dialector.Name()
+}
+
+// Migrations 返回按版本排序的所有迁移
+func (m *Migrator) Migrations() ([]*Migration, error) {
+	migrations := make(map[int64]*Migration)
+	if m.fsys != nil {
+		loaded, err := load(m.fsys, m.Dialect())
+		if err != nil {
+			return nil, err
+		}
+		migrations = loaded
+	}
+	for version, gm := range m.goMigrations {
+		if sm, ok := migrations[version]; ok {
+			return nil, fmt.Errorf("migrate: duplicate version %d: %s and %s", version, sm.Name, gm.Name)
+		}
+		migrations[version] = gm
+	}
+	return sorted(migrations), nil
+}
+
+// Up 按版本顺序执行未执行的迁移，steps 为 0 时执行全部，返回本次执行的迁移
+// 已执行的迁移被修改时返回 ErrChecksumMismatch，不执行任何迁移
+func (m *Migrator) Up(ctx context.Context, steps int) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		migrations, records, err := m.prepare(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			if r, ok := records[mg.Version]; ok && r.Checksum != mg.Checksum() {
+				return fmt.Errorf("%w: version %d_%s", ErrChecksumMismatch, mg.Version, mg.Name)
+			}
+		}
+		for _, mg := range migrations {
+			if steps > 0 && len(applied) >= steps {
+				break
+			}
+			if _, ok := records[mg.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mg); err != nil {
+				return err
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，steps 不大于 0 时回滚 1 个
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	var reverted []*Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		migrations, _, err := m.prepare(conn)
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]*Migration, len(migrations))
+		for _, mg := range migrations {
+			byVersion[mg.Version] = mg
+		}
+		var records []history
+		if err := conn.Table(m.Table).Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+		for _, r := range records {
+			mg, ok := byVersion[r.Version]
+			if !ok {
+				return fmt.Errorf("migrate: version %d_%s is %s", r.Version, r.Name, StatusMissing)
+			}
+			if !mg.hasDown() {
+				return fmt.Errorf("%w: version %d_%s", ErrNoDown, mg.Version, mg.Name)
+			}
+			if err := m.revert(ctx, conn, mg); err != nil {
+				return err
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status 返回所有迁移和历史记录的状态，按版本排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn := m.db.WithContext(ctx)
+	migrations, records, err := m.prepare(conn)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[int64]*Migration, len(migrations))
+	for _, mg := range migrations {
+		all[mg.Version] = mg
+	}
+	for version, r := range records {
+		if _, ok := all[version]; !ok {
+			all[version] = &Migration{Version: version, Name: r.Name}
+		}
+	}
+	list := sorted(all)
+	result := make([]Status, 0, len(list))
+	for _, mg := range list {
+		s := Status{Version: mg.Version, Name: mg.Name, Status: StatusPending}
+		if r, ok := records[mg.Version]; ok {
+			appliedAt := r.AppliedAt
+			s.AppliedAt = &appliedAt
+			switch {
+			case mg.Up == "" && mg.UpFunc == nil:
+				s.Status = StatusMissing
+			case r.Checksum != mg.Checksum():
+				s.Status = StatusModified
+			default:
+				s.Status = StatusApplied
+			}
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// prepare 创建历史表并读取迁移和已执行的记录
+func (m *Migrator) prepare(conn *gorm.DB) ([]*Migration, map[int64]history, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conn.Table(m.Table).AutoMigrate(&history{}); err != nil {
+		return nil, nil, err
+	}
+	var list []history
+	if err := conn.Table(m.Table).Find(&list).Error; err != nil {
+		return nil, nil, err
+	}
+	records := make(map[int64]history, len(list))
+	for _, r := range list {
+		records[r.Version] = r
+	}
+	return migrations, records, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *gorm.DB, mg *Migration) error {
+	begin := time.Now()
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := run(ctx, tx, mg.Up, mg.UpFunc); err != nil {
+			return err
+		}
+		return tx.Table(m.Table).Create(&history{
+			Version:     mg.Version,
+			Name:        mg.Name,
+			Checksum:    mg.Checksum(),
+			AppliedAt:   time.Now(),
+			ExecutionMs: time.Since(begin).Milliseconds(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: up %d_%s: %w", mg.Version, mg.Name, err)
+	}
+	logs.CtxInfo(ctx, "migration applied", "version", mg.Version, "name", mg.Name, "duration", time.Since(begin))
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *gorm.DB, mg *Migration) error {
+	begin := time.Now()
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := run(ctx, tx, mg.Down, mg.DownFunc); err != nil {
+			return err
+		}
+		return tx.Table(m.Table).Where("version = ?", mg.Version).Delete(&history{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: down %d_%s: %w", mg.Version, mg.Name, err)
+	}
+	logs.CtxInfo(ctx, "migration reverted", "version", mg.Version, "name", mg.Name, "duration", time.Since(begin))
+	return nil
+}
+
+func run(ctx context.Context, tx *gorm.DB, script string, fn Func) error {
+	if fn != nil {
+		return fn(ctx, tx)
+	}
+	for _, stmt := range splitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locked 在同一个连接上获取数据库锁后执行 fn，锁是会话级的，必须在同一连接上释放
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) lock(ctx context.Context, conn *gorm.DB) (func(), error) {
+	name := "thunder:migrate:" + m.Table
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	// 释放锁时不受 ctx 取消影响，否则锁会一直留在连接池的连接上
+	release := conn.WithContext(context.WithoutCancel(ctx))
+	switch m.Dialect() {
+	case "mysql":
+		var ok *int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&ok).Error; err != nil {
+			return nil, err
+		}
+		if ok == nil || *ok != 1 {
+			return nil, ErrLocked
+		}
+		return func() {
+			release.Exec("SELECT RELEASE_LOCK(?)", name)
+		}, nil
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		key := int64(h.Sum64())
+		deadline := time.Now().Add(timeout)
+		for {
+			var ok bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&ok).Error; err != nil {
+				return nil, err
+			}
+			if ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, ErrLocked
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return func() {
+			release.Exec("SELECT pg_advisory_unlock(?)", key)
+		}, nil
+	default:
+		logs.CtxWarn(ctx, "migration lock is not supported, make sure only one instance migrates", "dialect", m.Dialect())
+		return func() {}, nil
+	}
+}
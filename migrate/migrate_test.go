@@ -0,0 +1,140 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := db.DB(); err == nil {
+			_ = conn.Close()
+		}
+	})
+	return db
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_seed_users.up.sql":     {Data: []byte("INSERT INTO users (name) VALUES ('a');\nINSERT INTO users (name) VALUES ('b');")},
+	}
+}
+
+func statuses(t *testing.T, m *Migrator) map[int64]string {
+	t.Helper()
+	list, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[int64]string, len(list))
+	for _, s := range list {
+		result[s.Version] = s.Status
+	}
+	return result
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := New(db, testMigrations())
+	m.Register(3, "rename_users", func(ctx context.Context, tx *gorm.DB) error {
+		return tx.Exec("UPDATE users SET name = name || '!'").Error
+	}, func(ctx context.Context, tx *gorm.DB) error {
+		return tx.Exec("UPDATE users SET name = rtrim(name, '!')").Error
+	})
+
+	applied, err := m.Up(ctx, 1)
+	if err != nil || len(applied) != 1 || applied[0].Version != 1 {
+		t.Fatalf("up 1 step: %v %v", applied, err)
+	}
+	applied, err = m.Up(ctx, 0)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("up all: %v %v", applied, err)
+	}
+	var names []string
+	db.Table("users").Order("id").Pluck("name", &names)
+	if len(names) != 2 || names[0] != "a!" {
+		t.Fatalf("names = %v", names)
+	}
+	if applied, _ := m.Up(ctx, 0); len(applied) != 0 {
+		t.Fatalf("nothing should be pending, applied %v", applied)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 3 {
+		t.Fatalf("down go migration: %v %v", reverted, err)
+	}
+	// 0002 没有 down 文件，回滚失败且不修改历史
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDown) {
+		t.Fatalf("want ErrNoDown, got %v", err)
+	}
+	if got := statuses(t, m); got[1] != StatusApplied || got[2] != StatusApplied || got[3] != StatusPending {
+		t.Fatalf("statuses = %v", got)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	fsys := testMigrations()
+	if _, err := New(db, fsys).Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 修改已执行的迁移，同时新增一个待执行的迁移
+	fsys["0002_seed_users.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO users (name) VALUES ('c');")}
+	fsys["0003_add_email.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")}
+	m := New(db, fsys)
+	if _, err := m.Up(ctx, 0); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("want ErrChecksumMismatch, got %v", err)
+	}
+	if got := statuses(t, m); got[2] != StatusModified || got[3] != StatusPending {
+		t.Fatalf("mismatch should stop all migrations, statuses = %v", got)
+	}
+	// 历史中有记录但文件被删除
+	delete(fsys, "0002_seed_users.up.sql")
+	if got := statuses(t, New(db, fsys)); got[2] != StatusMissing {
+		t.Fatalf("statuses = %v", got)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	fsys := testMigrations()
+	fsys["0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO users (name) VALUES ('c');\nINSERT INTO missing VALUES (1);")}
+	m := New(db, fsys)
+	applied, err := m.Up(ctx, 0)
+	if err == nil || len(applied) != 2 {
+		t.Fatalf("broken migration should fail after 2 applied: %v %v", applied, err)
+	}
+	var count int64
+	db.Table("users").Count(&count)
+	if count != 2 {
+		t.Fatalf("statements of the failed migration should be rolled back, count = %d", count)
+	}
+	if got := statuses(t, m); got[3] != StatusPending {
+		t.Fatalf("statuses = %v", got)
+	}
+}
+
+func TestDuplicateVersion(t *testing.T) {
+	m := New(openTestDB(t), testMigrations())
+	m.Register(1, "go_init", func(context.Context, *gorm.DB) error { return nil }, nil)
+	if _, err := m.Migrations(); err == nil {
+		t.Fatal("go migration with the same version as a SQL migration should fail")
+	}
+}
@@ -0,0 +1,227 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Func Go 编写的迁移，在迁移事务中执行
+type Func func(ctx context.Context, tx *gorm.DB) error
+
+// Migration 一个版本的迁移，SQL 迁移来自 {version}_{name}.up.sql 和 {version}_{name}.down.sql，
+// 只在某种数据库上执行的文件加上方言后缀，例如 {version}_{name}.up.postgres.sql，优先于通用文件
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	UpFunc   Func
+	DownFunc Func
+}
+
+// Checksum 返回 up 迁移内容的 sha256，已执行的迁移被修改后与历史表中的记录不一致
+func (m *Migration) Checksum() string {
+	content := m.Up
+	if m.UpFunc != nil {
+		content = "go:" + m.Name
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Migration) hasDown() bool {
+	return m.Down != "" || m.DownFunc != nil
+}
+
+var fileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)(?:\.(mysql|postgres))?\.sql$`)
+
+// load 读取 fsys 根目录下的 SQL 迁移，只加载通用文件和 dialect 对应的文件
+func load(fsys fs.FS, dialect string) (map[int64]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make(map[int64]*Migration)
+	// 记录每个版本的 up/down 是否来自方言文件，方言文件覆盖通用文件
+	specific := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s: %w", entry.Name(), err)
+		}
+		name, direction, fileDialect := match[2], match[3], match[4]
+		if fileDialect != "" && fileDialect != dialect {
+			continue
+		}
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			migrations[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migrate: duplicate version %d: %s and %s", version, m.Name, name)
+		}
+		key := match[1] + "." + direction
+		if fileDialect == "" && specific[key] {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+		specific[key] = fileDialect != ""
+	}
+	for _, m := range migrations {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d_%s has no up migration", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+func sorted(migrations map[int64]*Migration) []*Migration {
+	list := make([]*Migration, 0, len(migrations))
+	for _, m := range migrations {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+var nameRegexp = regexp.MustCompile(`\W+`)
+
+// Create 在 dir 下创建一对空的 up/down 迁移文件，版本号为当前 UTC 时间 yyyyMMddHHmmss
+func Create(dir, name string) (up, down string, err error) {
+	name = strings.Trim(nameRegexp.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("migrate: migration name is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	prefix := time.Now().UTC().Format("20060102150405") + "_" + name
+	up = filepath.Join(dir, prefix+".up.sql")
+	down = filepath.Join(dir, prefix+".down.sql")
+	for _, path := range []string{up, down} {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_ = f.Close()
+	}
+	return up, down, nil
+}
+
+// splitStatements 按分号拆分多条 SQL，跳过引号、注释和 Postgres 的 $$ 引用中的分号，
+// MySQL 驱动默认不允许一次执行多条语句
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		start      int
+	)
+	n := len(script)
+	for i := 0; i < n; i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case c == '-' && i+1 < n && script[i+1] == '-':
+			for i < n && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i += end + 3
+			}
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					i = n
+				} else {
+					i += len(tag) + end + len(tag) - 1
+				}
+			}
+		case c == ';':
+			statements = appendStatement(statements, script[start:i])
+			start = i + 1
+		}
+	}
+	if start < n {
+		statements = appendStatement(statements, script[start:])
+	}
+	return statements
+}
+
+func skipQuoted(script string, i int, quote byte) int {
+	for j := i + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			// 连续两个引号是转义
+			if j+1 < len(script) && script[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(script)
+}
+
+var dollarTagRegexp = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func dollarTag(s string) string {
+	return dollarTagRegexp.FindString(s)
+}
+
+// appendStatement 忽略只有空白和注释的片段
+func appendStatement(statements []string, stmt string) []string {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" || stripComments(stmt) == "" {
+		return statements
+	}
+	return append(statements, stmt)
+}
+
+var blockCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// stripComments 去掉注释后的内容，仅用于判断片段是否为空
+func stripComments(stmt string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(stmt, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		b.WriteString(line)
+	}
+	return strings.TrimSpace(blockCommentRegexp.ReplaceAllString(b.String(), ""))
+}
@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- create table; with comment
+CREATE TABLE t (name VARCHAR(10) DEFAULT 'a;b');
+/* block; comment */
+INSERT INTO t VALUES ('it''s; ok');
+CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;
+-- trailing comment`
+	want := []string{
+		"-- create table; with comment\nCREATE TABLE t (name VARCHAR(10) DEFAULT 'a;b')",
+		"/* block; comment */\nINSERT INTO t VALUES ('it''s; ok')",
+		"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitStatements() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadDialect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":             {Data: []byte("generic up")},
+		"0001_init.down.sql":           {Data: []byte("generic down")},
+		"0001_init.up.postgres.sql":    {Data: []byte("postgres up")},
+		"0002_mysql_only.up.mysql.sql": {Data: []byte("mysql up")},
+		"README.md":                    {Data: []byte("ignored")},
+	}
+	migrations, err := load(fsys, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 {
+		t.Fatalf("expected 1 migration for postgres, got %d", len(migrations))
+	}
+	if m := migrations[1]; m.Up != "postgres up" || m.Down != "generic down" {
+		t.Fatalf("unexpected migration %+v", m)
+	}
+	migrations, err = load(fsys, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := sorted(migrations); len(list) != 2 || list[0].Up != "generic up" || list[1].Name != "mysql_only" {
+		t.Fatalf("unexpected mysql migrations %+v", list)
+	}
+}
+
+func TestLoadMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{"0001_init.down.sql": {Data: []byte("DROP TABLE t")}}
+	if _, err := load(fsys, "mysql"); err == nil {
+		t.Fatal("expected error for migration without up file")
+	}
+}
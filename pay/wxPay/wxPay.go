@@ -16,12 +16,12 @@ type WxPay struct {
 }
 
 type PayBody struct {
-	Description string  `json:"description"`
-	OutTradeNo  string  `json:"out_trade_no"`
-	TimeExpire  string  `json:"time_expire"`
-	Amount      float64 `json:"amount"`
-	OpenId      string  `json:"openId"`
-	ClientIp    string  `json:"clientIp"`
+	Description string `json:"description"`
+	OutTradeNo  string `json:"out_trade_no"`
+	TimeExpire  string `json:"time_expire"`
+	Amount      int64  `json:"amount"` //金额，单位分，types.Money 可以通过 Cents() 转换
+	OpenId      string `json:"openId"`
+	ClientIp    string `json:"clientIp"`
 }
 
 func Init(pay *config.WxPay) {
@@ -160,4 +160,4 @@ func H5Pay(ctx context.Context, body *PayBody) (string, error) {
 		return rsp.Response.H5Url, nil
 	}
 	return "", errors.New("fail")
-}
\ No newline at end of file
+}
@@ -1,6 +1,10 @@
 package req
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
 
 func GetInt64(c *gin.Context, key string) int64 {
 	value, ok := c.Get(key)
@@ -9,3 +13,19 @@ func GetInt64(c *gin.Context, key string) int64 {
 	}
 	return 0
 }
+
+type userIdKey struct{}
+
+// WithUserId 返回携带当前用户的 context，midd.Auth 认证通过后写入请求的 context
+func WithUserId(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userIdKey{}, userId)
+}
+
+// UserId 返回 context 中的当前用户，未登录时为空
+func UserId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	userId, _ := ctx.Value(userIdKey{}).(string)
+	return userId
+}
@@ -41,6 +41,8 @@ func Error(ctx *gin.Context, err error) {
 			ctx.Writer.WriteHeader(http.StatusBadRequest)
 		} else if errors.Is(er, errs.ErrUnauthorized) {
 			ctx.Writer.WriteHeader(http.StatusUnauthorized)
+		} else if errors.Is(er, errs.ErrForbidden) {
+			ctx.Writer.WriteHeader(http.StatusForbidden)
 		} else {
 			Fail(ctx, er)
 		}
@@ -14,7 +14,18 @@ type CloseIRouter interface {
 	IRouter
 	Close() error
 }
+
+// UseCustomMidd 挂载自定义中间件
+// 配置了 middlewares 时按配置的名称、顺序和路径范围构建管道，否则按旧的规则全局挂载 Cors、Auth、Tenant、Authz、RateLimit、Cache
 func UseCustomMidd(conf *config.Config, engin *gin.Engine) {
+	if len(conf.Middlewares) > 0 {
+		handlers, err := midd.Build(conf)
+		if err != nil {
+			panic(err)
+		}
+		engin.Use(handlers...)
+		return
+	}
 	if conf.Server != nil {
 		if len(conf.Server.GetCros()) > 0 {
 			engin.Use(midd.Cors(conf.Server))
@@ -25,8 +36,18 @@ func UseCustomMidd(conf *config.Config, engin *gin.Engine) {
 			engin.Use(midd.Auth(conf.Auth))
 		}
 	}
+	// Tenant 读取 Auth 写入的 tenantId，必须挂载在 Auth 之后
+	if conf.Tenant.GetEnable() {
+		engin.Use(midd.Tenant(conf.Tenant))
+	}
+	if conf.Authz.GetEnable() {
+		engin.Use(midd.Authorize(conf.Authz))
+	}
+	if conf.RateLimit.GetEnable() && len(conf.RateLimit.Rules) > 0 {
+		engin.Use(midd.RateLimit(conf.RateLimit))
+	}
 	if conf.Cache != nil {
-		if len(conf.Cache.GetNeedCache()) > 0 {
+		if conf.Cache.IsEnable() {
 			engin.Use(midd.Cache(conf.Cache))
 		}
 	}
@@ -13,7 +13,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/zhangc-zwl/thunder/config"
 	"github.com/zhangc-zwl/thunder/event"
+	"github.com/zhangc-zwl/thunder/midd"
 	"github.com/zhangc-zwl/thunder/pay/wxPay"
+	"github.com/zhangc-zwl/thunder/tools/jwt"
 )
 
 // Server 是我们应用的核心结构体
@@ -40,9 +42,17 @@ func NewServer(conf *config.Config) *Server {
 		}
 	}
 
-	engine := gin.Default() // 使用默认的中间件 (Logger, Recovery)
+	engine := gin.New()
+	// 访问日志需要最先挂载，保证后续中间件都能拿到带 request_id 的 logger
+	engine.Use(midd.AccessLog(), midd.Recovery(conf.Server), midd.StickyPrimary())
 	//自定义的一些中间件，可通过配置文件开启，减少代码重复书写
 	UseCustomMidd(conf, engine)
+	// 发布 JWT 公钥，供其他服务验签，开启 Auth 时需要将该路径加入 ignores
+	if path := conf.Jwt.GetJwksPath(); path != "" {
+		engine.GET(path, func(c *gin.Context) {
+			c.JSON(http.StatusOK, jwt.GetJWKS())
+		})
+	}
 	return &Server{
 		Engine: engine,
 		conf:   conf,
@@ -0,0 +1,219 @@
+package tenant
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultColumn 默认的租户列
+const DefaultColumn = "tenant_id"
+
+// Plugin 自动按租户隔离数据的 GORM 插件，只作用于有租户列的模型：
+// 查询、更新、删除时追加 tenant_id = ? 条件，创建时填充租户列，
+// context 中没有租户且没有调用 Skip 时返回 ErrNoTenant，Raw 和 Exec 执行的 SQL 不受影响
+// 更新时不会修改租户列，创建时的 ON CONFLICT 更新（包括 Save 更新不到记录时的 upsert）只更新当前租户的记录
+//
+//	gdb.Use(&tenant.Plugin{Column: conf.Tenant.GetColumn()})
+type Plugin struct {
+	// Column 租户列名，默认 DefaultColumn
+	Column string
+}
+
+func (p *Plugin) Name() string {
+	return "thunder:tenant"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	if p.Column == "" {
+		p.Column = DefaultColumn
+	}
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("thunder:tenant_query", p.filter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("thunder:tenant_row", p.filter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("thunder:tenant_update", p.filterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("thunder:tenant_delete", p.filterWrite); err != nil {
+		return err
+	}
+	return cb.Create().Before("gorm:create").Register("thunder:tenant_create", p.assign)
+}
+
+// tenantField 返回模型的租户字段和当前租户，不需要处理时返回 nil
+func (p *Plugin) tenantField(db *gorm.DB) (*schema.Field, string) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || Skipped(stmt.Context) {
+		return nil, ""
+	}
+	field := stmt.Schema.LookUpField(p.Column)
+	if field == nil {
+		return nil, ""
+	}
+	id, ok := FromContext(stmt.Context)
+	if !ok {
+		_ = db.AddError(ErrNoTenant)
+		return nil, ""
+	}
+	return field, id
+}
+
+func (p *Plugin) filter(db *gorm.DB) {
+	field, id := p.tenantField(db)
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: convert(field, id)},
+	}})
+}
+
+// filterWrite 没有条件的批量更新和删除交给 GORM 报 ErrMissingWhereClause，
+// 不能因为追加了租户条件就变成更新或删除整个租户的数据
+func (p *Plugin) filterWrite(db *gorm.DB) {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses["WHERE"]; !ok && !db.AllowGlobalUpdate && !hasPrimaryValues(stmt) {
+		return
+	}
+	p.filter(db)
+}
+
+// filterUpdate 更新时不修改租户列，Save 的结构体租户为空时也不会把记录移出当前租户
+// 需要在租户之间迁移数据时使用 Skip
+func (p *Plugin) filterUpdate(db *gorm.DB) {
+	field, _ := p.tenantField(db)
+	if field == nil {
+		return
+	}
+	db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+	p.filterWrite(db)
+}
+
+func (p *Plugin) assign(db *gorm.DB) {
+	field, id := p.tenantField(db)
+	if field == nil {
+		return
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := p.assignOne(db, field, reflect.Indirect(rv.Index(i)), id); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := p.assignOne(db, field, rv, id); err != nil {
+			_ = db.AddError(err)
+			return
+		}
+	}
+	p.guardUpsert(db, field, id)
+}
+
+// guardUpsert 限制 ON CONFLICT 的更新范围，否则用其他租户记录的主键 Save 时，
+// 更新因租户条件匹配不到记录，GORM 改为 upsert，会把其他租户的记录覆盖并改成当前租户
+// MySQL 的 ON DUPLICATE KEY UPDATE 不支持条件，返回 ErrTenantUpsert
+func (p *Plugin) guardUpsert(db *gorm.DB, field *schema.Field, id string) {
+	stmt := db.Statement
+	c, ok := stmt.Clauses["ON CONFLICT"]
+	if !ok {
+		return
+	}
+	onConflict, ok := c.Expression.(clause.OnConflict)
+	if !ok || onConflict.DoNothing || (!onConflict.UpdateAll && len(onConflict.DoUpdates) == 0) {
+		return
+	}
+	if stmt.Dialector.Name() == "mysql" {
+		_ = db.AddError(ErrTenantUpsert)
+		return
+	}
+	if onConflict.UpdateAll {
+		onConflict.UpdateAll = false
+		onConflict.DoUpdates = clause.AssignmentColumns(upsertColumns(stmt))
+	}
+	updates := make(clause.Set, 0, len(onConflict.DoUpdates))
+	for _, assignment := range onConflict.DoUpdates {
+		if assignment.Column.Name != field.DBName {
+			updates = append(updates, assignment)
+		}
+	}
+	onConflict.DoUpdates = updates
+	if len(onConflict.Columns) == 0 && onConflict.OnConstraint == "" {
+		for _, pk := range stmt.Schema.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: pk.DBName})
+		}
+	}
+	if len(updates) == 0 {
+		onConflict.DoNothing = true
+	} else {
+		onConflict.Where.Exprs = append(onConflict.Where.Exprs,
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: convert(field, id)})
+	}
+	stmt.AddClause(onConflict)
+}
+
+// upsertColumns 与 GORM 展开 UpdateAll 的规则一致：不更新主键、创建时间和由数据库生成默认值的列
+func upsertColumns(stmt *gorm.Statement) []string {
+	selects, restricted := stmt.SelectAndOmitColumns(true, true)
+	columns := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[name]
+		if v, ok := selects[name]; (ok && !v) || (!ok && restricted) {
+			continue
+		}
+		if field.PrimaryKey || field.AutoCreateTime > 0 || !field.Creatable {
+			continue
+		}
+		if field.HasDefaultValue && field.DefaultValueInterface == nil && !strings.EqualFold(field.DefaultValue, "NULL") {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+func (p *Plugin) assignOne(db *gorm.DB, field *schema.Field, rv reflect.Value, id string) error {
+	ctx := db.Statement.Context
+	current, zero := field.ValueOf(ctx, rv)
+	if zero {
+		return field.Set(ctx, rv, convert(field, id))
+	}
+	if fmt.Sprint(current) != id {
+		return ErrTenantMismatch
+	}
+	return nil
+}
+
+func hasPrimaryValues(stmt *gorm.Statement) bool {
+	if !stmt.ReflectValue.IsValid() || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	_, values := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	return len(values) > 0
+}
+
+// convert 按租户列的类型转换租户标识，整数列使用整数参数
+func convert(field *schema.Field, id string) any {
+	switch field.DataType {
+	case schema.Int:
+		if v, err := strconv.ParseInt(id, 10, 64); err == nil {
+			return v
+		}
+	case schema.Uint:
+		if v, err := strconv.ParseUint(id, 10, 64); err == nil {
+			return v
+		}
+	}
+	return id
+}
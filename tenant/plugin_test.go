@@ -0,0 +1,191 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+type order struct {
+	ID       int64
+	TenantId string
+	Name     string
+}
+
+// plain 没有租户列，不受插件影响
+type plain struct {
+	ID   int64
+	Name string
+}
+
+// sqlRecorder 记录执行的 SQL，DryRun 模式下也会调用 Trace
+type sqlRecorder struct {
+	logger.Interface
+	mu   sync.Mutex
+	sqls []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sqls = append(r.sqls, sql)
+}
+
+func (r *sqlRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sqls) == 0 {
+		return ""
+	}
+	return r.sqls[len(r.sqls)-1]
+}
+
+func openDB(t *testing.T, dialector gorm.Dialector, dryRun bool) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: dryRun, DisableAutomaticPing: true, SkipDefaultTransaction: true, Logger: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(&Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, rec
+}
+
+func TestPluginSQL(t *testing.T) {
+	db, rec := openDB(t, sqlite.Open(":memory:"), true)
+	ctx := WithTenant(context.Background(), "acme")
+	cases := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			name: "query",
+			run:  func() error { return db.WithContext(ctx).Where("name = ?", "a").Find(&[]order{}).Error },
+			want: "SELECT * FROM `orders` WHERE name = \"a\" AND `orders`.`tenant_id` = \"acme\"",
+		},
+		{
+			name: "count",
+			run:  func() error { var n int64; return db.WithContext(ctx).Model(&order{}).Count(&n).Error },
+			want: "SELECT count(*) FROM `orders` WHERE `orders`.`tenant_id` = \"acme\"",
+		},
+		{
+			name: "update",
+			run:  func() error { return db.WithContext(ctx).Model(&order{ID: 1}).Update("name", "b").Error },
+			want: "UPDATE `orders` SET `name`=\"b\" WHERE `orders`.`tenant_id` = \"acme\" AND `id` = 1",
+		},
+		{
+			name: "save",
+			run:  func() error { return db.WithContext(ctx).Save(&order{ID: 1, Name: "b"}).Error },
+			want: "UPDATE `orders` SET `name`=\"b\" WHERE `orders`.`tenant_id` = \"acme\" AND `id` = 1",
+		},
+		{
+			name: "delete",
+			run:  func() error { return db.WithContext(ctx).Delete(&order{ID: 1}).Error },
+			want: "DELETE FROM `orders` WHERE `orders`.`tenant_id` = \"acme\" AND `orders`.`id` = 1",
+		},
+		{
+			name: "create",
+			run:  func() error { return db.WithContext(ctx).Create(&order{Name: "a"}).Error },
+			want: "INSERT INTO `orders` (`tenant_id`,`name`) VALUES (\"acme\",\"a\") RETURNING `id`",
+		},
+		{
+			name: "upsert",
+			run: func() error {
+				return db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&order{ID: 1, Name: "a"}).Error
+			},
+			want: "INSERT INTO `orders` (`tenant_id`,`name`,`id`) VALUES (\"acme\",\"a\",1) ON CONFLICT (`id`) DO UPDATE SET `name`=`excluded`.`name` WHERE `orders`.`tenant_id` = \"acme\"  RETURNING `id`",
+		},
+		{
+			name: "skip",
+			run:  func() error { return db.WithContext(Skip(ctx)).Find(&[]order{}).Error },
+			want: "SELECT * FROM `orders`",
+		},
+		{
+			name: "no tenant column",
+			run:  func() error { return db.WithContext(context.Background()).Find(&[]plain{}).Error },
+			want: "SELECT * FROM `plains`",
+		},
+	}
+	for _, c := range cases {
+		if err := c.run(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if sql := rec.last(); sql != c.want {
+			t.Fatalf("%s sql\n got: %s\nwant: %s", c.name, sql, c.want)
+		}
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	db, _ := openDB(t, sqlite.Open(":memory:"), true)
+	ctx := WithTenant(context.Background(), "acme")
+	if err := db.WithContext(context.Background()).Find(&[]order{}).Error; !errors.Is(err, ErrNoTenant) {
+		t.Fatalf("query without tenant: %v", err)
+	}
+	if err := db.WithContext(ctx).Create(&order{TenantId: "other"}).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("create for another tenant: %v", err)
+	}
+	// 没有条件的批量更新不会因为租户条件变成更新整个租户
+	if err := db.WithContext(ctx).Model(&order{}).Update("name", "b").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("update without where: %v", err)
+	}
+
+	// MySQL 的 upsert 无法按租户限制
+	my, _ := openDB(t, mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/db", SkipInitializeWithVersion: true}), true)
+	err := my.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&order{ID: 1}).Error
+	if !errors.Is(err, ErrTenantUpsert) {
+		t.Fatalf("mysql upsert: %v", err)
+	}
+	if err := my.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&order{ID: 1}).Error; err != nil {
+		t.Fatalf("mysql insert ignore should be allowed: %v", err)
+	}
+}
+
+func TestSaveCannotTakeOverOtherTenant(t *testing.T) {
+	db, _ := openDB(t, sqlite.Open(filepath.Join(t.TempDir(), "test.db")), false)
+	if err := db.AutoMigrate(&order{}); err != nil {
+		t.Fatal(err)
+	}
+	acme := WithTenant(context.Background(), "acme")
+	other := WithTenant(context.Background(), "other")
+	if err := db.WithContext(acme).Create(&order{ID: 1, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 其他租户使用相同主键 Save：更新匹配不到记录，upsert 也不会覆盖
+	if err := db.WithContext(other).Save(&order{ID: 1, Name: "hijack"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	if err := db.WithContext(Skip(context.Background())).First(&got, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.TenantId != "acme" || got.Name != "a" {
+		t.Fatalf("record of another tenant was overwritten: %+v", got)
+	}
+	// 本租户 Save 正常更新和插入
+	if err := db.WithContext(acme).Save(&order{ID: 1, Name: "b"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WithContext(acme).Save(&order{ID: 2, Name: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	db.WithContext(acme).Model(&order{}).Order("id").Pluck("name", &names)
+	if strings.Join(names, ",") != "b,c" {
+		t.Fatalf("names = %v", names)
+	}
+}
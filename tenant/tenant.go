@@ -0,0 +1,49 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoTenant 操作带租户列的表时 context 中没有租户，也没有通过 Skip 显式跳过
+	ErrNoTenant = errors.New("tenant: no tenant in context")
+	// ErrTenantMismatch 写入的数据属于其他租户
+	ErrTenantMismatch = errors.New("tenant: record belongs to another tenant")
+	// ErrTenantUpsert 数据库的 upsert 不支持按租户限制更新，无法防止覆盖其他租户的记录
+	ErrTenantUpsert = errors.New("tenant: upsert is not supported on this database")
+)
+
+type tenantKey struct{}
+
+type skipKey struct{}
+
+// WithTenant 返回携带租户标识的 context，HTTP 请求由 midd.Tenant 设置，后台任务需要自己设置
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext 返回 context 中的租户标识
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Skip 返回不做租户过滤的 context，用于管理后台、跨租户统计等需要访问全部数据的操作
+//
+//	gorms.DB(tenant.Skip(ctx)).Find(&allOrders)
+func Skip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// Skipped context 是否跳过租户过滤
+func Skipped(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	skip, _ := ctx.Value(skipKey{}).(bool)
+	return skip
+}
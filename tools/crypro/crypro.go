@@ -6,6 +6,7 @@ import (
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -57,6 +58,12 @@ func Sha1(input string) string {
 	return hashString
 }
 
+// Sha256 计算字符串的 sha256 摘要，返回十六进制字符串
+func Sha256(input string) string {
+	hash := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(hash[:])
+}
+
 func encodeBase64(input []byte) string {
 	return base64.URLEncoding.EncodeToString(input)
 }
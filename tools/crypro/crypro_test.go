@@ -8,3 +8,35 @@ import (
 func TestEncryptString(t *testing.T) {
 	fmt.Println(Md5WithSalt("ms-co!@#$12398mmx", "ZfnXw"))
 }
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := NewKeyring(Key{Id: "v1", Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := old.Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewKeyring(Key{Id: "v2", Secret: "abcdefghijklmnopqrstuvwxyz012345"}, Key{Id: "v1", Secret: "0123456789abcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := rotated.Decrypt(ciphertext)
+	if err != nil || string(plaintext) != "hello" {
+		t.Fatalf("decrypt with rotated keyring: %q %v", plaintext, err)
+	}
+	tampered := []byte(ciphertext)
+	if i := len(tampered) - 5; tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	if _, err := old.Decrypt(string(tampered)); err == nil {
+		t.Fatal("tampered ciphertext should fail")
+	}
+	next, _ := rotated.Encrypt([]byte("hello"))
+	if _, err := old.Decrypt(next); err == nil {
+		t.Fatal("old keyring should not know the new key")
+	}
+}
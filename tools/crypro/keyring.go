@@ -0,0 +1,114 @@
+package crypro
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrUnknownKey 密文使用的密钥不在密钥环中
+	ErrUnknownKey = errors.New("crypro: unknown key id")
+	// ErrInvalidCiphertext 密文格式错误或被篡改
+	ErrInvalidCiphertext = errors.New("crypro: invalid ciphertext")
+)
+
+// Key AES 密钥，Secret 为 16、24、32 字节的原始字符串或其 base64 编码
+type Key struct {
+	Id     string
+	Secret string
+}
+
+// Keyring AES-GCM 密钥环，使用第一个密钥加密，所有密钥都可以解密，
+// 轮换密钥时把新密钥放在第一个，旧密钥保留到数据重新加密完成
+// 密文格式为 {id}:{base64url(nonce + 密文)}，GCM 会校验密文是否被篡改
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("crypro: at least one key is required")
+	}
+	k := &Keyring{primary: keys[0].Id, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if key.Id == "" || strings.Contains(key.Id, ":") {
+			return nil, fmt.Errorf("crypro: invalid key id %q", key.Id)
+		}
+		if _, ok := k.aeads[key.Id]; ok {
+			return nil, fmt.Errorf("crypro: duplicate key id %q", key.Id)
+		}
+		secret, err := keyBytes(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("crypro: key %q: %w", key.Id, err)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.Id] = aead
+	}
+	return k, nil
+}
+
+func keyBytes(secret string) ([]byte, error) {
+	switch len(secret) {
+	case 16, 24, 32:
+		return []byte(secret), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err == nil {
+		switch len(decoded) {
+		case 16, 24, 32:
+			return decoded, nil
+		}
+	}
+	return nil, errors.New("key must be 16, 24 or 32 bytes")
+}
+
+// Encrypt 使用第一个密钥加密
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(k.primary))
+	return k.primary + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 按密文中的密钥标识选择密钥解密
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	id, payload, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return nil, ErrInvalidCiphertext
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// Primary 返回加密使用的密钥标识
+func (k *Keyring) Primary() string {
+	return k.primary
+}
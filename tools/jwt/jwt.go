@@ -5,14 +5,32 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenExpired = errors.New("token has expired")
+	ErrInvalidToken = errors.New("invalid token")
 )
 
 // CustomClaims 自定义声明类型，内嵌 jwt.RegisteredClaims
 // RegisteredClaims 包含官方定义的标准字段：(iss, sub, aud, exp, nbf, iat, jti)
 // 我们这里增加了自定义字段 UserID 和 Username
 type CustomClaims struct {
-	UserId string `json:"userId"`
+	UserId   string `json:"userId"`
 	Username string `json:"username"`
+	// Roles 用户角色，由 midd.Authorize 等授权中间件使用
+	Roles []string `json:"roles,omitempty"`
+	// Permissions 用户直接拥有的权限
+	Permissions []string `json:"perms,omitempty"`
+	// TenantId 用户所属租户，由 midd.Tenant 解析
+	TenantId string `json:"tid,omitempty"`
+	// TokenType 令牌类型 access | refresh，单独签发的 token 为空，视为 access
+	TokenType string `json:"typ,omitempty"`
+	// Family 令牌族，同一次登录轮换出来的所有令牌共享一个 Family，用于检测 refresh token 重放
+	Family string `json:"fam,omitempty"`
+	// IssuedAtMs 毫秒精度的签发时间，iat 只精确到秒，无法区分与 RevokeUser 同一秒签发的令牌
+	IssuedAtMs int64 `json:"iatms,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -20,12 +38,23 @@ type CustomClaims struct {
 type JWT struct {
 	// SecretKey 用于签名的密钥，应该是保密的
 	SecretKey []byte
+	// AccessExpire 令牌对中 access token 的有效期
+	AccessExpire time.Duration
+	// RefreshExpire 令牌对中 refresh token 的有效期
+	RefreshExpire time.Duration
+	// Store 保存吊销和 refresh token 轮换状态，为 nil 时不支持吊销
+	Store TokenStore
+	// Keys 非对称密钥集合，配置后使用其中的签名密钥签发 token，并按 kid 验签
+	// 为 nil 时使用 SecretKey 进行 HS256 签名
+	Keys *KeySet
 }
 
 // NewJWT 创建一个 JWT 工具实例
 func NewJWT(secretKey string) *JWT {
 	return &JWT{
-		SecretKey: []byte(secretKey),
+		SecretKey:     []byte(secretKey),
+		AccessExpire:  24 * time.Hour,
+		RefreshExpire: 7 * 24 * time.Hour,
 	}
 }
 
@@ -36,10 +65,24 @@ func (j *JWT) GenerateToken(claims CustomClaims, expirationTime time.Duration) (
 	// jwt.NewNumericDate 是一个辅助函数，用于将 time.Time 转换为 JWT 使用的 Unix 时间戳
 	claims.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expirationTime))
 	// 设置签发时间
-	claims.RegisteredClaims.IssuedAt = jwt.NewNumericDate(time.Now())
+	now := time.Now()
+	claims.RegisteredClaims.IssuedAt = jwt.NewNumericDate(now)
+	claims.IssuedAtMs = now.UnixMilli()
+	// 设置唯一 ID，吊销时以 jti 为准
+	if claims.RegisteredClaims.ID == "" {
+		claims.RegisteredClaims.ID = uuid.NewString()
+	}
 	// 设置签发人 (可选)
 	// claims.RegisteredClaims.Issuer = "my-project-name"
 
+	// 配置了非对称密钥时，使用当前的签名密钥并在头部写入 kid
+	if j.Keys != nil {
+		key := j.Keys.Signing()
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.Kid
+		return token.SignedString(key.SignKey)
+	}
+
 	// 使用指定的签名方法和声明创建一个新的 Token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -53,21 +96,15 @@ func (j *JWT) ParseToken(tokenString string) (*CustomClaims, error) {
 	// 1. tokenString: 要解析的 token 字符串
 	// 2. &CustomClaims{}: 一个空的声明对象指针，用于告诉库如何解码 payload
 	// 3. Keyfunc: 一个回调函数，用于提供验证签名所需的密钥
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 重要的安全校验：检查 token 使用的签名算法是否是我们期望的 HMAC 算法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.SecretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, j.keyFunc)
 
 	if err != nil {
 		// 这里处理各种解析错误
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, errors.New("token has expired")
+			return nil, ErrTokenExpired
 		}
 		// 其他错误，例如签名无效、格式错误等
-		return nil, errors.New("invalid token")
+		return nil, ErrInvalidToken
 	}
 
 	// 检查 token 是否有效，并且声明是否是我们定义的类型
@@ -75,5 +112,34 @@ func (j *JWT) ParseToken(tokenString string) (*CustomClaims, error) {
 		return claims, nil
 	}
 
-	return nil, errors.New("invalid token")
+	return nil, ErrInvalidToken
+}
+
+// keyFunc 根据 token 头部选择验签密钥
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && j.Keys != nil {
+		key, found := j.Keys.Get(kid)
+		if !found {
+			return nil, errors.New("unknown kid")
+		}
+		// 重要的安全校验：token 的签名算法必须与密钥配置的算法一致，防止算法混淆攻击
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.VerifyKey, nil
+	}
+	// 没有 kid 的 token 按 HMAC 校验，兼容切换到非对称密钥之前签发的 token
+	// 重要的安全校验：检查 token 使用的签名算法是否是我们期望的 HMAC 算法
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(j.SecretKey) == 0 {
+		return nil, errors.New("unexpected signing method")
+	}
+	return j.SecretKey, nil
+}
+
+// JWKS 导出公钥集合，未配置非对称密钥时返回空集合
+func (j *JWT) JWKS() JWKS {
+	if j.Keys == nil {
+		return JWKS{Keys: []JSONWebKey{}}
+	}
+	return j.Keys.JWKS()
 }
@@ -0,0 +1,188 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhangc-zwl/thunder/config"
+)
+
+// Key 一个带 kid 的签名密钥
+// SignKey 为空时该密钥只用于验签
+type Key struct {
+	Kid       string
+	Method    jwt.SigningMethod
+	SignKey   crypto.PrivateKey
+	VerifyKey crypto.PublicKey
+}
+
+// KeySet 支持多个密钥同时生效，签名使用 signing 密钥，验签按 token 头中的 kid 查找
+// 轮换密钥时，新密钥作为签名密钥，旧密钥只保留公钥，直到旧密钥签发的 token 全部过期
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string
+}
+
+// NewKeySet 创建密钥集合，signingKid 为空时使用第一个带私钥的密钥签名
+func NewKeySet(signingKid string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		if key.Kid == "" {
+			return nil, errors.New("jwt key kid is required")
+		}
+		if _, ok := ks.keys[key.Kid]; ok {
+			return nil, fmt.Errorf("duplicate jwt key kid %s", key.Kid)
+		}
+		ks.keys[key.Kid] = key
+		ks.order = append(ks.order, key.Kid)
+		if ks.signing == nil && key.SignKey != nil && (signingKid == "" || signingKid == key.Kid) {
+			ks.signing = key
+		}
+	}
+	if ks.signing == nil {
+		return nil, fmt.Errorf("no private key found for signing kid %q", signingKid)
+	}
+	return ks, nil
+}
+
+// Signing 返回当前用于签名的密钥
+func (ks *KeySet) Signing() *Key {
+	return ks.signing
+}
+
+// Get 按 kid 查找密钥
+func (ks *KeySet) Get(kid string) (*Key, bool) {
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// LoadKey 从配置的 PEM 文件中加载密钥
+func LoadKey(conf *config.JwtKey) (*Key, error) {
+	method := jwt.GetSigningMethod(conf.GetAlgorithm())
+	if method == nil {
+		return nil, fmt.Errorf("jwt key %s: unsupported algorithm %s", conf.GetKid(), conf.GetAlgorithm())
+	}
+	key := &Key{Kid: conf.GetKid(), Method: method}
+	if path := conf.GetPrivateKey(); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			key.SignKey, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		case *jwt.SigningMethodECDSA:
+			key.SignKey, err = jwt.ParseECPrivateKeyFromPEM(data)
+		case *jwt.SigningMethodEd25519:
+			key.SignKey, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		default:
+			err = fmt.Errorf("unsupported algorithm %s", method.Alg())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: parse private key: %w", key.Kid, err)
+		}
+		// 从私钥推导公钥
+		if signer, ok := key.SignKey.(crypto.Signer); ok {
+			key.VerifyKey = signer.Public()
+		}
+	}
+	if path := conf.GetPublicKey(); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		case *jwt.SigningMethodECDSA:
+			key.VerifyKey, err = jwt.ParseECPublicKeyFromPEM(data)
+		case *jwt.SigningMethodEd25519:
+			key.VerifyKey, err = jwt.ParseEdPublicKeyFromPEM(data)
+		default:
+			err = fmt.Errorf("unsupported algorithm %s", method.Alg())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: parse public key: %w", key.Kid, err)
+		}
+	}
+	if key.VerifyKey == nil {
+		return nil, fmt.Errorf("jwt key %s: privateKey or publicKey is required", key.Kid)
+	}
+	return key, nil
+}
+
+// LoadKeySet 根据 jwt 配置加载全部密钥，未配置 keys 时返回 nil
+func LoadKeySet(conf *config.Jwt) (*KeySet, error) {
+	if conf == nil || len(conf.Keys) == 0 {
+		return nil, nil
+	}
+	keys := make([]*Key, 0, len(conf.Keys))
+	for _, kc := range conf.Keys {
+		key, err := LoadKey(kc)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(conf.GetSigningKid(), keys...)
+}
+
+// JSONWebKey JWKS 中的单个公钥，字段含义见 RFC 7517/7518/8037
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 公钥集合文档
+type JWKS struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS 导出所有非对称密钥的公钥，供其他服务验签
+func (ks *KeySet) JWKS() JWKS {
+	doc := JWKS{Keys: []JSONWebKey{}}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		jwk := JSONWebKey{Kid: key.Kid, Alg: key.Method.Alg(), Use: "sig"}
+		switch pub := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeSegment(pub.N.Bytes())
+			jwk.E = encodeSegment(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = encodeSegment(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = encodeSegment(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encodeSegment(pub)
+		default:
+			continue
+		}
+		doc.Keys = append(doc.Keys, jwk)
+	}
+	return doc
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,164 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func testKeys(t *testing.T) []*Key {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*Key{
+		{Kid: "rsa", Method: jwt.SigningMethodRS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		{Kid: "ec", Method: jwt.SigningMethodES256, SignKey: ecKey, VerifyKey: &ecKey.PublicKey},
+		{Kid: "ed", Method: jwt.SigningMethodEdDSA, SignKey: edKey, VerifyKey: edPub},
+	}
+}
+
+func TestAsymmetricRoundTrip(t *testing.T) {
+	keys := testKeys(t)
+	for _, key := range keys {
+		ks, err := NewKeySet(key.Kid, keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j := &JWT{Keys: ks}
+		token, err := j.GenerateToken(CustomClaims{UserId: "1"}, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: %v", key.Kid, err)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &CustomClaims{})
+		if err != nil || parsed.Header["kid"] != key.Kid {
+			t.Fatalf("%s: token should carry its kid, got %v", key.Kid, parsed.Header["kid"])
+		}
+		claims, err := j.ParseToken(token)
+		if err != nil || claims.UserId != "1" {
+			t.Fatalf("%s: round trip failed: %v", key.Kid, err)
+		}
+	}
+}
+
+func TestKeyRotationAndWrongKid(t *testing.T) {
+	keys := testKeys(t)
+	old := &JWT{Keys: mustKeySet(t, "rsa", keys[0])}
+	token, err := old.GenerateToken(CustomClaims{UserId: "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 轮换后新密钥签名，旧密钥只保留公钥，旧 token 仍然可以验签
+	rotated := &JWT{Keys: mustKeySet(t, "ec", keys[1], &Key{Kid: "rsa", Method: keys[0].Method, VerifyKey: keys[0].VerifyKey})}
+	if _, err := rotated.ParseToken(token); err != nil {
+		t.Fatalf("token signed by the retired key should still verify: %v", err)
+	}
+	if rotated.Keys.Signing().Kid != "ec" {
+		t.Fatalf("signing kid = %s", rotated.Keys.Signing().Kid)
+	}
+	// kid 不在密钥集合中
+	unknown := &JWT{Keys: mustKeySet(t, "ed", keys[2])}
+	if _, err := unknown.ParseToken(token); err == nil {
+		t.Fatal("token with an unknown kid should be rejected")
+	}
+	// kid 存在但签名密钥不同
+	forged := &JWT{Keys: mustKeySet(t, "rsa", &Key{Kid: "rsa", Method: keys[0].Method, SignKey: mustRSA(t), VerifyKey: nil})}
+	forgedToken, err := forged.GenerateToken(CustomClaims{UserId: "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.ParseToken(forgedToken); err == nil {
+		t.Fatal("token signed by another key with the same kid should be rejected")
+	}
+	// 没有 kid 时按 HMAC 校验，未配置 SecretKey 时拒绝
+	hs := NewJWT("secret")
+	hsToken, err := hs.GenerateToken(CustomClaims{UserId: "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.ParseToken(hsToken); err == nil {
+		t.Fatal("HMAC token should be rejected without a secret key")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := testKeys(t)
+	doc := mustKeySet(t, "", keys...).JWKS()
+	if len(doc.Keys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(doc.Keys))
+	}
+	rsaJwk, ecJwk, edJwk := doc.Keys[0], doc.Keys[1], doc.Keys[2]
+	if rsaJwk.Kty != "RSA" || rsaJwk.Kid != "rsa" || rsaJwk.Alg != "RS256" || rsaJwk.E != "AQAB" || rsaJwk.N == "" {
+		t.Fatalf("unexpected rsa jwk %+v", rsaJwk)
+	}
+	if ecJwk.Kty != "EC" || ecJwk.Crv != "P-256" || len(ecJwk.X) != 43 || len(ecJwk.Y) != 43 {
+		t.Fatalf("unexpected ec jwk %+v", ecJwk)
+	}
+	if edJwk.Kty != "OKP" || edJwk.Crv != "Ed25519" || edJwk.Alg != "EdDSA" || len(edJwk.X) != 43 {
+		t.Fatalf("unexpected ed25519 jwk %+v", edJwk)
+	}
+}
+
+func TestLoadKeyFromPEM(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ec.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadKey(&config.JwtKey{Kid: gptr.Of("k1"), Algorithm: gptr.Of("ES256"), PrivateKey: gptr.Of(path)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.VerifyKey == nil || !key.VerifyKey.(*ecdsa.PublicKey).Equal(&ecKey.PublicKey) {
+		t.Fatal("public key should be derived from the private key")
+	}
+	if _, err := LoadKey(&config.JwtKey{Kid: gptr.Of("k2"), Algorithm: gptr.Of("XX256")}); err == nil {
+		t.Fatal("unsupported algorithm should fail")
+	}
+}
+
+func mustKeySet(t *testing.T, signingKid string, keys ...*Key) *KeySet {
+	t.Helper()
+	ks, err := NewKeySet(signingKid, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func mustRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -1,6 +1,11 @@
 package jwt
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/zhangc-zwl/thunder/config"
+)
 
 var _jwt *JWT
 
@@ -9,6 +14,30 @@ func Init(secretKey string) {
 	_jwt = NewJWT(secretKey)
 }
 
+// InitWithConfig 根据 jwt 配置初始化，令牌对的有效期取自 expire 和 refresh
+// 配置了 keys 时使用非对称密钥签名，密钥加载失败会直接 panic
+// store 用于令牌吊销和 refresh token 轮换，传 nil 时不支持吊销
+func InitWithConfig(conf *config.Jwt, store TokenStore) {
+	keys, err := LoadKeySet(conf)
+	if err != nil {
+		panic(err)
+	}
+	j := NewJWT(conf.GetSecret())
+	j.AccessExpire = conf.GetExpire()
+	j.RefreshExpire = conf.GetRefresh()
+	j.Store = store
+	j.Keys = keys
+	_jwt = j
+}
+
+// GetJWKS 返回当前的公钥集合
+func GetJWKS() JWKS {
+	if _jwt == nil {
+		return JWKS{Keys: []JSONWebKey{}}
+	}
+	return _jwt.JWKS()
+}
+
 func GenerateToken(claims CustomClaims, expirationTime time.Duration) (string, error) {
 	return _jwt.GenerateToken(claims, expirationTime)
 }
@@ -24,3 +53,27 @@ func GenToken(userId string, username string, expirationTime time.Duration) (str
 func ParseToken(tokenString string) (*CustomClaims, error) {
 	return _jwt.ParseToken(tokenString)
 }
+
+func GenTokenPair(ctx context.Context, userId string, username string) (*TokenPair, error) {
+	claims := CustomClaims{
+		UserId:   userId,
+		Username: username,
+	}
+	return _jwt.GenerateTokenPair(ctx, claims)
+}
+
+func Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return _jwt.Refresh(ctx, refreshToken)
+}
+
+func Revoke(ctx context.Context, claims *CustomClaims) error {
+	return _jwt.Revoke(ctx, claims)
+}
+
+func RevokeUser(ctx context.Context, userId string) error {
+	return _jwt.RevokeUser(ctx, userId)
+}
+
+func CheckRevoked(ctx context.Context, claims *CustomClaims) error {
+	return _jwt.CheckRevoked(ctx, claims)
+}
@@ -0,0 +1,204 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TokenStore 保存令牌吊销和 refresh token 轮换所需的状态
+type TokenStore interface {
+	// Revoke 将 id（jti 或 family）加入黑名单，ttl 过后自动移除
+	Revoke(ctx context.Context, id string, ttl time.Duration) error
+	// IsRevoked 判断 id 是否在黑名单中
+	IsRevoked(ctx context.Context, id string) (bool, error)
+	// RevokeUser 记录用户的吊销时间，在此之前签发的令牌全部失效
+	RevokeUser(ctx context.Context, userId string, at time.Time, ttl time.Duration) error
+	// UserRevokedAt 获取用户的吊销时间，未吊销时返回零值
+	UserRevokedAt(ctx context.Context, userId string) (time.Time, error)
+	// SetFamily 记录令牌族当前唯一有效的 refresh token jti
+	SetFamily(ctx context.Context, family string, jti string, ttl time.Duration) error
+	// GetFamily 获取令牌族当前有效的 refresh token jti，不存在时返回空字符串
+	GetFamily(ctx context.Context, family string) (string, error)
+	// RotateFamily 原子地比较并替换令牌族当前的 jti，只有当前值等于 old 时才替换为 next 并返回 true，
+	// 同一个 refresh token 并发刷新时只有一个请求能成功
+	RotateFamily(ctx context.Context, family string, old string, next string, ttl time.Duration) (bool, error)
+}
+
+// RedisStore 基于 Redis 的 TokenStore，多实例共享吊销状态
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore 创建 RedisStore，prefix 为空时使用 "JWT"
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "JWT"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.prefix+":revoked:"+id, 1, ttl).Err()
+}
+
+func (s *RedisStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+":revoked:"+id).Result()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *RedisStore) RevokeUser(ctx context.Context, userId string, at time.Time, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+":user:"+userId, at.UnixMilli(), ttl).Err()
+}
+
+func (s *RedisStore) UserRevokedAt(ctx context.Context, userId string) (time.Time, error) {
+	result, err := s.client.Get(ctx, s.prefix+":user:"+userId).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// 兼容之前以秒保存的吊销时间
+	if unix < 1e12 {
+		return time.Unix(unix, 0), nil
+	}
+	return time.UnixMilli(unix), nil
+}
+
+func (s *RedisStore) SetFamily(ctx context.Context, family string, jti string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+":family:"+family, jti, ttl).Err()
+}
+
+func (s *RedisStore) GetFamily(ctx context.Context, family string) (string, error) {
+	result, err := s.client.Get(ctx, s.prefix+":family:"+family).Result()
+	if err != nil && errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return result, err
+}
+
+// rotateFamilyScript 令牌族的比较并替换：KEYS[1] 令牌族，ARGV[1] 旧 jti，ARGV[2] 新 jti，ARGV[3] 过期毫秒数
+var rotateFamilyScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+func (s *RedisStore) RotateFamily(ctx context.Context, family string, old string, next string, ttl time.Duration) (bool, error) {
+	n, err := rotateFamilyScript.Run(ctx, s.client, []string{s.prefix + ":family:" + family}, old, next, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// MemoryStore 进程内的 TokenStore，适用于单实例部署和测试
+type MemoryStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	users   map[string]memoryEntry
+	family  map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value    string
+	revoked  time.Time
+	expireAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		revoked: make(map[string]time.Time),
+		users:   make(map[string]memoryEntry),
+		family:  make(map[string]memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Revoke(_ context.Context, id string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[id] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expireAt, ok := s.revoked[id]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(expireAt) {
+		delete(s.revoked, id)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) RevokeUser(_ context.Context, userId string, at time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userId] = memoryEntry{revoked: at, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) UserRevokedAt(_ context.Context, userId string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.users[userId]
+	if !ok || time.Now().After(entry.expireAt) {
+		delete(s.users, userId)
+		return time.Time{}, nil
+	}
+	return entry.revoked, nil
+}
+
+func (s *MemoryStore) SetFamily(_ context.Context, family string, jti string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.family[family] = memoryEntry{value: jti, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) GetFamily(_ context.Context, family string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.family[family]
+	if !ok || time.Now().After(entry.expireAt) {
+		delete(s.family, family)
+		return "", nil
+	}
+	return entry.value, nil
+}
+
+func (s *MemoryStore) RotateFamily(_ context.Context, family string, old string, next string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.family[family]
+	if !ok || time.Now().After(entry.expireAt) || entry.value != old {
+		return false, nil
+	}
+	s.family[family] = memoryEntry{value: next, expireAt: time.Now().Add(ttl)}
+	return true, nil
+}
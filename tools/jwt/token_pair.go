@@ -0,0 +1,194 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrTokenRevoked     = errors.New("token has been revoked")
+	ErrTokenReused      = errors.New("refresh token reused")
+	ErrInvalidTokenType = errors.New("invalid token type")
+	ErrNoTokenStore     = errors.New("token store is not configured")
+)
+
+// TokenPair 登录或刷新后返回给客户端的令牌对
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	RefreshToken     string    `json:"refreshToken"`
+	AccessExpiresAt  time.Time `json:"accessExpiresAt"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// GenerateTokenPair 签发一对新的 access/refresh token，并开启一个新的令牌族
+func (j *JWT) GenerateTokenPair(ctx context.Context, claims CustomClaims) (*TokenPair, error) {
+	family, refreshId := uuid.NewString(), uuid.NewString()
+	pair, err := j.issuePair(claims, family, refreshId)
+	if err != nil {
+		return nil, err
+	}
+	if j.Store != nil {
+		if err := j.Store.SetFamily(ctx, family, refreshId, j.RefreshExpire); err != nil {
+			return nil, err
+		}
+	}
+	return pair, nil
+}
+
+// Refresh 使用 refresh token 换取新的令牌对，旧的 refresh token 立即失效
+// 如果一个已经被轮换掉的 refresh token 再次被使用，说明令牌可能已泄露，整个令牌族都会被吊销
+func (j *JWT) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if j.Store == nil {
+		return nil, ErrNoTokenStore
+	}
+	claims, err := j.ParseToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh || claims.Family == "" {
+		return nil, ErrInvalidTokenType
+	}
+	next := CustomClaims{
+		UserId:      claims.UserId,
+		Username:    claims.Username,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		TenantId:    claims.TenantId,
+	}
+	refreshId := uuid.NewString()
+	pair, err := j.issuePair(next, claims.Family, refreshId)
+	if err != nil {
+		return nil, err
+	}
+	// 比较并替换在一个原子操作中完成，同一个 refresh token 并发刷新时只有一个请求能成功，其余视为重放
+	rotated, err := j.Store.RotateFamily(ctx, claims.Family, claims.ID, refreshId, j.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// 重放检测：吊销整个令牌族，包括由它签发的 access token
+		if err := j.Store.Revoke(ctx, claims.Family, j.RefreshExpire); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+	// 令牌族已被吊销时，新轮换出的令牌也属于该令牌族，同样无法使用
+	if err := j.CheckRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := j.Store.Revoke(ctx, claims.ID, remaining(claims)); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke 吊销令牌，登出时传入 access token 或 refresh token 的 claims 即可
+// 会同时吊销该令牌所在的令牌族，使对应的 refresh token 无法再刷新
+func (j *JWT) Revoke(ctx context.Context, claims *CustomClaims) error {
+	if j.Store == nil {
+		return ErrNoTokenStore
+	}
+	if claims.ID != "" {
+		if err := j.Store.Revoke(ctx, claims.ID, remaining(claims)); err != nil {
+			return err
+		}
+	}
+	if claims.Family != "" {
+		return j.Store.Revoke(ctx, claims.Family, j.RefreshExpire)
+	}
+	return nil
+}
+
+// RevokeUser 吊销用户在此之前签发的所有令牌，用于封禁用户或修改密码后强制下线
+func (j *JWT) RevokeUser(ctx context.Context, userId string) error {
+	if j.Store == nil {
+		return ErrNoTokenStore
+	}
+	ttl := j.RefreshExpire
+	if j.AccessExpire > ttl {
+		ttl = j.AccessExpire
+	}
+	return j.Store.RevokeUser(ctx, userId, time.Now(), ttl)
+}
+
+// CheckRevoked 检查令牌是否已被吊销，未配置 Store 时总是返回 nil
+func (j *JWT) CheckRevoked(ctx context.Context, claims *CustomClaims) error {
+	if j.Store == nil {
+		return nil
+	}
+	ids := []string{claims.ID, claims.Family}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		revoked, err := j.Store.IsRevoked(ctx, id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	revokedAt, err := j.Store.UserRevokedAt(ctx, claims.UserId)
+	if err != nil {
+		return err
+	}
+	if !revokedAt.IsZero() && !issuedAt(claims).After(revokedAt) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// issuedAt 令牌的签发时间，优先使用毫秒精度的 iatms，之前签发的令牌只有秒级的 iat
+func issuedAt(claims *CustomClaims) time.Time {
+	if claims.IssuedAtMs > 0 {
+		return time.UnixMilli(claims.IssuedAtMs)
+	}
+	if claims.IssuedAt != nil {
+		return claims.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// issuePair 签发令牌对，refresh token 使用指定的 jti，由调用方写入令牌族
+func (j *JWT) issuePair(claims CustomClaims, family string, refreshId string) (*TokenPair, error) {
+	now := time.Now()
+	access := claims
+	access.TokenType = TokenTypeAccess
+	access.Family = family
+	access.ID = uuid.NewString()
+	accessToken, err := j.GenerateToken(access, j.AccessExpire)
+	if err != nil {
+		return nil, err
+	}
+	refresh := claims
+	refresh.TokenType = TokenTypeRefresh
+	refresh.Family = family
+	refresh.ID = refreshId
+	refreshToken, err := j.GenerateToken(refresh, j.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  now.Add(j.AccessExpire),
+		RefreshExpiresAt: now.Add(j.RefreshExpire),
+	}, nil
+}
+
+// remaining 令牌剩余的有效期，黑名单只需要保留到令牌自然过期
+func remaining(claims *CustomClaims) time.Duration {
+	if claims.ExpiresAt == nil {
+		return 0
+	}
+	return time.Until(claims.ExpiresAt.Time)
+}
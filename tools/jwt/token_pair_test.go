@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	ctx := context.Background()
+	j := NewJWT("secret")
+	j.Store = NewMemoryStore()
+	pair, err := j.GenerateTokenPair(ctx, CustomClaims{UserId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := j.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧的 refresh token 再次使用，触发重放检测
+	if _, err := j.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("expected reuse to be rejected, got %v", err)
+	}
+	claims, err := j.ParseToken(next.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Revoke(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.CheckRevoked(ctx, claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected revoked, got %v", err)
+	}
+	if _, err := j.Refresh(ctx, next.RefreshToken); err == nil {
+		t.Fatal("refresh token of a revoked family should be rejected")
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	j := NewJWT("secret")
+	j.Store = NewMemoryStore()
+	pair, err := j.GenerateTokenPair(ctx, CustomClaims{UserId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 20
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		success int
+		reused  int
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := j.Refresh(ctx, pair.RefreshToken)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				success++
+			case errors.Is(err, ErrTokenReused), errors.Is(err, ErrTokenRevoked):
+				reused++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if success != 1 || reused != n-1 {
+		t.Fatalf("exactly one refresh should win, got success=%d reused=%d", success, reused)
+	}
+}
+
+func TestRevokeUserSameSecond(t *testing.T) {
+	ctx := context.Background()
+	j := NewJWT("secret")
+	j.Store = NewMemoryStore()
+	before, err := j.GenerateTokenPair(ctx, CustomClaims{UserId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := j.RevokeUser(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	after, err := j.GenerateTokenPair(ctx, CustomClaims{UserId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := j.ParseToken(before.AccessToken)
+	if err := j.CheckRevoked(ctx, old); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("token issued before RevokeUser should be revoked, got %v", err)
+	}
+	// 与吊销同一秒内、但在吊销之后签发的令牌仍然有效
+	fresh, _ := j.ParseToken(after.AccessToken)
+	if err := j.CheckRevoked(ctx, fresh); err != nil {
+		t.Fatalf("token issued after RevokeUser should be valid, got %v", err)
+	}
+}
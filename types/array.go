@@ -0,0 +1,201 @@
+package types
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// StringArray 字符串数组，Postgres 上使用原生 text[] 列，可以用 @>、ANY 查询和建 GIN 索引，
+// 其他数据库以 JSON 存储
+type StringArray []string
+
+func (a *StringArray) Scan(value any) error {
+	items, err := scanArray(value)
+	if err != nil || items == nil {
+		*a = nil
+		return err
+	}
+	*a = items
+	return nil
+}
+
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(a))
+	return string(data), err
+}
+
+func (a StringArray) GormValue(_ context.Context, db *gorm.DB) clause.Expr {
+	if a == nil {
+		return clause.Expr{SQL: "NULL"}
+	}
+	if isPostgres(db) {
+		items := make([]string, len(a))
+		for i, s := range a {
+			items[i] = quotePgElement(s)
+		}
+		return clause.Expr{SQL: "?", Vars: []any{"{" + strings.Join(items, ",") + "}"}}
+	}
+	value, _ := a.Value()
+	return clause.Expr{SQL: "?", Vars: []any{value}}
+}
+
+func (StringArray) GormDataType() string {
+	return "string_array"
+}
+
+func (StringArray) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	return arrayDBDataType(db, "text[]")
+}
+
+// Int64Array 整数数组，Postgres 上使用原生 bigint[] 列，其他数据库以 JSON 存储
+type Int64Array []int64
+
+func (a *Int64Array) Scan(value any) error {
+	items, err := scanArray(value)
+	if err != nil || items == nil {
+		*a = nil
+		return err
+	}
+	result := make(Int64Array, len(items))
+	for i, item := range items {
+		if result[i], err = strconv.ParseInt(item, 10, 64); err != nil {
+			return fmt.Errorf("types: invalid int64 array element %q", item)
+		}
+	}
+	*a = result
+	return nil
+}
+
+func (a Int64Array) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]int64(a))
+	return string(data), err
+}
+
+func (a Int64Array) GormValue(_ context.Context, db *gorm.DB) clause.Expr {
+	if a == nil {
+		return clause.Expr{SQL: "NULL"}
+	}
+	if isPostgres(db) {
+		items := make([]string, len(a))
+		for i, v := range a {
+			items[i] = strconv.FormatInt(v, 10)
+		}
+		return clause.Expr{SQL: "?", Vars: []any{"{" + strings.Join(items, ",") + "}"}}
+	}
+	value, _ := a.Value()
+	return clause.Expr{SQL: "?", Vars: []any{value}}
+}
+
+func (Int64Array) GormDataType() string {
+	return "int64_array"
+}
+
+func (Int64Array) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	return arrayDBDataType(db, "bigint[]")
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db != nil && db.Dialector != nil && db.Dialector.Name() == "postgres"
+}
+
+func arrayDBDataType(db *gorm.DB, pgType string) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return pgType
+	case "mysql":
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
+
+// scanArray 解析 Postgres 数组 {a,"b c"} 或 JSON 数组，所有元素以字符串返回
+func scanArray(value any) ([]string, error) {
+	data, err := scanBytes(value)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	s := strings.TrimSpace(string(data))
+	if strings.HasPrefix(s, "{") {
+		return parsePgArray(s)
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	items := make([]string, len(raws))
+	for i, raw := range raws {
+		if len(raw) > 0 && raw[0] == '"' {
+			if err := json.Unmarshal(raw, &items[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		items[i] = string(raw)
+	}
+	return items, nil
+}
+
+// parsePgArray 解析一维 Postgres 数组字面量，NULL 元素解析为空字符串
+func parsePgArray(s string) ([]string, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("types: invalid postgres array %q", s)
+	}
+	body := s[1 : len(s)-1]
+	items := make([]string, 0)
+	if body == "" {
+		return items, nil
+	}
+	for i := 0; i <= len(body); {
+		var item strings.Builder
+		if i < len(body) && body[i] == '"' {
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				item.WriteByte(body[i])
+			}
+			if i >= len(body) {
+				return nil, fmt.Errorf("types: unterminated element in postgres array %q", s)
+			}
+			i++
+			items = append(items, item.String())
+		} else {
+			end := strings.IndexByte(body[i:], ',')
+			if end < 0 {
+				end = len(body) - i
+			}
+			elem := strings.TrimSpace(body[i : i+end])
+			if strings.EqualFold(elem, "NULL") {
+				elem = ""
+			}
+			items = append(items, elem)
+			i += end
+		}
+		if i < len(body) && body[i] != ',' {
+			return nil, fmt.Errorf("types: invalid postgres array %q", s)
+		}
+		i++
+	}
+	return items, nil
+}
+
+func quotePgElement(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sync"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/crypro"
+)
+
+// ErrNoKeyring 没有调用 InitEncryption 或 SetKeyring
+var ErrNoKeyring = errors.New("types: encryption keyring is not initialized")
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *crypro.Keyring
+)
+
+// InitEncryption 使用配置中的 crypto.aesKeys 初始化 EncryptedString 的密钥
+func InitEncryption(conf *config.Crypto) error {
+	keys := make([]crypro.Key, 0, len(conf.GetAesKeys()))
+	for _, k := range conf.GetAesKeys() {
+		keys = append(keys, crypro.Key{Id: k.GetId(), Secret: k.GetKey()})
+	}
+	k, err := crypro.NewKeyring(keys...)
+	if err != nil {
+		return err
+	}
+	SetKeyring(k)
+	return nil
+}
+
+// SetKeyring 设置 EncryptedString 使用的密钥环
+func SetKeyring(k *crypro.Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyring = k
+}
+
+func getKeyring() (*crypro.Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	if keyring == nil {
+		return nil, ErrNoKeyring
+	}
+	return keyring, nil
+}
+
+// EncryptedString 写入数据库时使用 AES-GCM 加密，读取时自动解密，适合手机号、身份证号等敏感字段
+// 每次加密使用随机 nonce，相同明文的密文不同，因此不能用于等值查询和唯一索引，
+// 需要查询时另存一列 crypro.Sha256 摘要；空字符串不加密
+type EncryptedString string
+
+func (s *EncryptedString) Scan(value any) error {
+	data, err := scanBytes(value)
+	if err != nil || len(data) == 0 {
+		*s = ""
+		return err
+	}
+	k, err := getKeyring()
+	if err != nil {
+		return err
+	}
+	plaintext, err := k.Decrypt(string(data))
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	k, err := getKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt([]byte(s))
+}
+
+func (s EncryptedString) String() string {
+	return string(s)
+}
+
+func (EncryptedString) GormDataType() string {
+	return "text"
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON 以 JSON 存储任意类型，MySQL 使用 JSON 列，Postgres 使用 JSONB 列，
+// 序列化为接口响应时直接输出 Data
+//
+//	type Order struct {
+//		ID      int64
+//		Address types.JSON[Address]
+//	}
+//	order.Address.Data.City
+type JSON[T any] struct {
+	Data T
+}
+
+// NewJSON 创建 JSON 值
+func NewJSON[T any](data T) JSON[T] {
+	return JSON[T]{Data: data}
+}
+
+func (j *JSON[T]) Scan(value any) error {
+	data, err := scanBytes(value)
+	if err != nil {
+		return err
+	}
+	var zero T
+	j.Data = zero
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &j.Data)
+}
+
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Data)
+}
+
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.Data)
+}
+
+func (JSON[T]) GormDataType() string {
+	return "json"
+}
+
+func (JSON[T]) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "JSONB"
+	case "mysql":
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MoneyScale 金额保留的小数位数
+const MoneyScale = 2
+
+// Money 金额，单位为元，使用十进制定点数避免 float64 的精度问题，
+// 数据库中存为 DECIMAL(20,2)，JSON 序列化为字符串 "12.34"，反序列化同时支持字符串和数字
+type Money struct {
+	decimal.Decimal
+}
+
+// NewMoney 解析金额字符串，例如 "12.34"
+func NewMoney(s string) (Money, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Decimal: d}, nil
+}
+
+// MoneyFromCents 由分创建金额
+func MoneyFromCents(cents int64) Money {
+	return Money{Decimal: decimal.New(cents, -MoneyScale)}
+}
+
+// Cents 转换为分，超出两位的小数四舍五入，微信支付等接口使用分作为单位
+func (m Money) Cents() int64 {
+	return m.Decimal.Shift(MoneyScale).Round(0).IntPart()
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Decimal: m.Decimal.Add(o.Decimal)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Decimal: m.Decimal.Sub(o.Decimal)}
+}
+
+// Mul 乘以数量，结果按 MoneyScale 四舍五入
+func (m Money) Mul(quantity int64) Money {
+	return Money{Decimal: m.Decimal.Mul(decimal.NewFromInt(quantity)).Round(MoneyScale)}
+}
+
+// String 固定输出两位小数
+func (m Money) String() string {
+	return m.Decimal.StringFixed(MoneyScale)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.String() + `"`), nil
+}
+
+func (Money) GormDataType() string {
+	return "decimal"
+}
+
+func (Money) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "NUMERIC(20,2)"
+	case "sqlite":
+		return "TEXT"
+	default:
+		return "DECIMAL(20,2)"
+	}
+}
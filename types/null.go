@@ -0,0 +1,65 @@
+package types
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+)
+
+// Null 可以为 NULL 的列，在 sql.Null 的基础上支持 JSON，无效值序列化为 null
+//
+//	type User struct {
+//		Age types.Null[int]
+//	}
+type Null[T any] struct {
+	sql.Null[T]
+}
+
+// NewNull 创建有效的值
+func NewNull[T any](v T) Null[T] {
+	return Null[T]{Null: sql.Null[T]{V: v, Valid: true}}
+}
+
+// NullFromPtr 指针为 nil 时返回无效值
+func NullFromPtr[T any](v *T) Null[T] {
+	if v == nil {
+		return Null[T]{}
+	}
+	return NewNull(*v)
+}
+
+// Ptr 无效值返回 nil
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// ValueOr 无效值返回 def
+func (n Null[T]) ValueOr(def T) T {
+	if !n.Valid {
+		return def
+	}
+	return n.V
+}
+
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func (n *Null[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*n = Null[T]{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
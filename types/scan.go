@@ -0,0 +1,19 @@
+package types
+
+import (
+	"fmt"
+)
+
+// scanBytes 统一数据库驱动返回的 []byte 和 string，NULL 返回 nil
+func scanBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("types: cannot scan %T", value)
+	}
+}
@@ -3,20 +3,21 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
-	"errors"
 	"strconv"
 	"strings"
 )
 
+// ArrayString 以 JSON 字符串存储的字符串数组，Postgres 原生数组使用 StringArray
 type ArrayString []string
 
 func (a *ArrayString) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
+	bytes, err := scanBytes(value)
+	if err != nil || bytes == nil {
+		*a = nil
+		return err
 	}
 	var as ArrayString
-	err := json.Unmarshal(bytes, &as)
+	err = json.Unmarshal(bytes, &as)
 	*a = as
 	return err
 }
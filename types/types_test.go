@@ -0,0 +1,102 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhangc-zwl/thunder/config"
+	"github.com/zhangc-zwl/thunder/tools/gptr"
+)
+
+func TestArrayScan(t *testing.T) {
+	var s StringArray
+	if err := s.Scan(`{a,"b,c","d\"e",NULL}`); err != nil {
+		t.Fatal(err)
+	}
+	if want := (StringArray{"a", "b,c", `d"e`, ""}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("got %q, want %q", s, want)
+	}
+	if err := s.Scan([]byte(`["x","y"]`)); err != nil || !reflect.DeepEqual(s, StringArray{"x", "y"}) {
+		t.Fatalf("json scan: %q %v", s, err)
+	}
+	var n Int64Array
+	if err := n.Scan("{1,2,3}"); err != nil || !reflect.DeepEqual(n, Int64Array{1, 2, 3}) {
+		t.Fatalf("int scan: %v %v", n, err)
+	}
+	var legacy ArrayString
+	if err := legacy.Scan(`["a"]`); err != nil || !reflect.DeepEqual(legacy, ArrayString{"a"}) {
+		t.Fatalf("ArrayString should scan string values: %v %v", legacy, err)
+	}
+	if err := legacy.Scan(nil); err != nil || legacy != nil {
+		t.Fatalf("ArrayString should scan NULL: %v %v", legacy, err)
+	}
+}
+
+func TestJSONAndNull(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	var j JSON[address]
+	if err := j.Scan(`{"city":"Hangzhou"}`); err != nil || j.Data.City != "Hangzhou" {
+		t.Fatalf("scan: %+v %v", j, err)
+	}
+	out, _ := json.Marshal(struct {
+		Address JSON[address] `json:"address"`
+		Age     Null[int]     `json:"age"`
+		Score   Null[int]     `json:"score"`
+	}{Address: j, Score: NewNull(0)})
+	if string(out) != `{"address":{"city":"Hangzhou"},"age":null,"score":0}` {
+		t.Fatalf("unexpected json %s", out)
+	}
+	var n Null[int]
+	if err := json.Unmarshal([]byte("7"), &n); err != nil || !n.Valid || n.V != 7 {
+		t.Fatalf("unmarshal: %+v %v", n, err)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	m, err := NewMoney("19.99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Mul(3).String(); got != "59.97" {
+		t.Fatalf("Mul = %s", got)
+	}
+	if m.Cents() != 1999 || MoneyFromCents(1).String() != "0.01" {
+		t.Fatalf("cents conversion failed: %d", m.Cents())
+	}
+	var body struct {
+		Amount Money `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount":0.1}`), &body); err != nil || body.Amount.Add(MoneyFromCents(20)).String() != "0.30" {
+		t.Fatalf("unmarshal number: %v %v", body.Amount, err)
+	}
+	out, _ := json.Marshal(body)
+	if string(out) != `{"amount":"0.10"}` {
+		t.Fatalf("unexpected json %s", out)
+	}
+}
+
+func TestEncryptedString(t *testing.T) {
+	SetKeyring(nil)
+	if _, err := EncryptedString("secret").Value(); !errors.Is(err, ErrNoKeyring) {
+		t.Fatalf("expected ErrNoKeyring, got %v", err)
+	}
+	err := InitEncryption(&config.Crypto{AesKeys: []*config.AesKey{
+		{Id: gptr.Of("k1"), Key: gptr.Of("0123456789abcdef")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := EncryptedString("13800000000").Value()
+	if err != nil || !strings.HasPrefix(v.(string), "k1:") || strings.Contains(v.(string), "13800000000") {
+		t.Fatalf("unexpected ciphertext %v %v", v, err)
+	}
+	var s EncryptedString
+	if err := s.Scan(v); err != nil || s != "13800000000" {
+		t.Fatalf("decrypt: %q %v", s, err)
+	}
+}